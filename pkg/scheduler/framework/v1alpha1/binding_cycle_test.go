@@ -0,0 +1,213 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kubernetes/pkg/scheduler/apis/config"
+)
+
+const (
+	reservePlugin1 = "reserve-plugin-1"
+	reservePlugin2 = "reserve-plugin-2"
+	permitPlugin   = "permit-plugin"
+)
+
+// TestReservePlugin records whether Reserve and Unreserve were called, and can be
+// configured to fail Reserve.
+type TestReservePlugin struct {
+	name               string
+	failReserve        bool
+	numReserveCalled   int
+	numUnreserveCalled int
+}
+
+func (pl *TestReservePlugin) Name() string { return pl.name }
+
+func (pl *TestReservePlugin) Reserve(pc *PluginContext, p *v1.Pod, nodeName string) *Status {
+	pl.numReserveCalled++
+	if pl.failReserve {
+		return NewStatus(Error, "injecting reserve failure.")
+	}
+	return nil
+}
+
+func (pl *TestReservePlugin) Unreserve(pc *PluginContext, p *v1.Pod, nodeName string) {
+	pl.numUnreserveCalled++
+}
+
+// TestPermitPlugin returns a configurable status and wait duration from Permit.
+type TestPermitPlugin struct {
+	statusCode Code
+	waitTime   time.Duration
+}
+
+func (pl *TestPermitPlugin) Name() string { return permitPlugin }
+
+func (pl *TestPermitPlugin) Permit(pc *PluginContext, p *v1.Pod, nodeName string) (*Status, time.Duration) {
+	if pl.statusCode == Success {
+		return nil, 0
+	}
+	return NewStatus(pl.statusCode, "injected by test"), pl.waitTime
+}
+
+func TestRunReservePlugins(t *testing.T) {
+	tests := []struct {
+		name          string
+		plugins       []*TestReservePlugin
+		wantErr       bool
+		wantRuns      []int // expected numReserveCalled per plugin
+		wantUnreserve []int // expected numUnreserveCalled per plugin
+	}{
+		{
+			name: "all reserve plugins succeed",
+			plugins: []*TestReservePlugin{
+				{name: reservePlugin1},
+				{name: reservePlugin2},
+			},
+			wantRuns:      []int{1, 1},
+			wantUnreserve: []int{0, 0},
+		},
+		{
+			name: "second reserve plugin fails, first gets unreserved",
+			plugins: []*TestReservePlugin{
+				{name: reservePlugin1},
+				{name: reservePlugin2, failReserve: true},
+			},
+			wantErr:       true,
+			wantRuns:      []int{1, 1},
+			wantUnreserve: []int{1, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Registry{}
+			plugins := &config.Plugins{Reserve: &config.PluginSet{}}
+			for _, pl := range tt.plugins {
+				pl := pl
+				r[pl.name] = func(_ *runtime.Unknown, _ FrameworkHandle) (Plugin, error) { return pl, nil }
+				plugins.Reserve.Enabled = append(plugins.Reserve.Enabled, config.Plugin{Name: pl.name})
+			}
+
+			f, err := NewFramework(r, plugins, nil)
+			if err != nil {
+				t.Fatalf("Failed to create framework for testing: %v", err)
+			}
+
+			status := f.RunReservePlugins(pc, pod, "node1")
+			if tt.wantErr && status.IsSuccess() {
+				t.Errorf("Expected status to be non-success.")
+			}
+			if !tt.wantErr && !status.IsSuccess() {
+				t.Errorf("Expected status to be success, got: %v", status.Message())
+			}
+
+			for i, pl := range tt.plugins {
+				if pl.numReserveCalled != tt.wantRuns[i] {
+					t.Errorf("plugin %d: Reserve called %d times, want %d", i, pl.numReserveCalled, tt.wantRuns[i])
+				}
+				if pl.numUnreserveCalled != tt.wantUnreserve[i] {
+					t.Errorf("plugin %d: Unreserve called %d times, want %d", i, pl.numUnreserveCalled, tt.wantUnreserve[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRunPermitPlugins(t *testing.T) {
+	tests := []struct {
+		name    string
+		plugin  *TestPermitPlugin
+		action  func(wp WaitingPod)
+		wantErr bool
+	}{
+		{
+			name:   "permit allows immediately",
+			plugin: &TestPermitPlugin{statusCode: Success},
+		},
+		{
+			name:   "permit waits then is allowed",
+			plugin: &TestPermitPlugin{statusCode: Wait, waitTime: time.Minute},
+			action: func(wp WaitingPod) { wp.Allow(permitPlugin) },
+		},
+		{
+			name:    "permit waits then is rejected",
+			plugin:  &TestPermitPlugin{statusCode: Wait, waitTime: time.Minute},
+			action:  func(wp WaitingPod) { wp.Reject("rejected by test") },
+			wantErr: true,
+		},
+		{
+			name:    "permit waits and times out",
+			plugin:  &TestPermitPlugin{statusCode: Wait, waitTime: 10 * time.Millisecond},
+			wantErr: true,
+		},
+		{
+			name:    "permit returns error",
+			plugin:  &TestPermitPlugin{statusCode: Error},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Registry{
+				permitPlugin: func(_ *runtime.Unknown, _ FrameworkHandle) (Plugin, error) { return tt.plugin, nil },
+			}
+			plugins := &config.Plugins{
+				Permit: &config.PluginSet{
+					Enabled: []config.Plugin{{Name: permitPlugin}},
+				},
+			}
+			f, err := NewFramework(r, plugins, nil)
+			if err != nil {
+				t.Fatalf("Failed to create framework for testing: %v", err)
+			}
+
+			testPod := &v1.Pod{}
+			testPod.UID = types.UID("test-pod")
+
+			if tt.action != nil {
+				go func() {
+					// Poll until RunPermitPlugins has registered the waiting pod,
+					// rather than assuming a fixed sleep is long enough.
+					var wp WaitingPod
+					for wp == nil {
+						wp = f.GetWaitingPod(testPod.UID)
+						if wp == nil {
+							time.Sleep(time.Millisecond)
+						}
+					}
+					tt.action(wp)
+				}()
+			}
+
+			status := f.RunPermitPlugins(pc, testPod, "node1")
+			if tt.wantErr && status.IsSuccess() {
+				t.Errorf("Expected status to be non-success.")
+			}
+			if !tt.wantErr && !status.IsSuccess() {
+				t.Errorf("Expected status to be success, got: %v", status.Message())
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"sort"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// DefaultPreemptionName is the name under which the default preemption PostFilter
+// plugin is registered.
+const DefaultPreemptionName = "DefaultPreemption"
+
+// DefaultPreemption is a reference PostFilter plugin. Given the map of nodes that
+// failed the Filter phase, it looks among the nodes where preemption might help
+// (i.e. that weren't marked UnschedulableAndUnresolvable) for one where evicting
+// some of its lower-priority pods would let pod fit, using the same predicates
+// that rejected it during Filter, and nominates the first such node it finds.
+type DefaultPreemption struct {
+	fh FrameworkHandle
+	// selectVictims reports the set of pods that would need to be evicted from
+	// nodeName for pod to fit there, and whether such a set exists. It exists so
+	// tests can stub out the predicate check; in production it consults
+	// fh.GetNodeInfo for nodeName's occupants and re-runs the Filter predicates
+	// with lower-priority pods hypothetically evicted, lowest priority first.
+	selectVictims func(pc *PluginContext, pod *v1.Pod, nodeName string) ([]*v1.Pod, bool)
+}
+
+var _ PostFilterPlugin = &DefaultPreemption{}
+
+// Name returns name of the plugin.
+func (pl *DefaultPreemption) Name() string {
+	return DefaultPreemptionName
+}
+
+// NewDefaultPreemption is the factory for the DefaultPreemption plugin.
+func NewDefaultPreemption(_ *runtime.Unknown, fh FrameworkHandle) (Plugin, error) {
+	pl := &DefaultPreemption{fh: fh}
+	pl.selectVictims = pl.selectVictimsByRerunningFilters
+	return pl, nil
+}
+
+// selectVictimsByRerunningFilters is the production selectVictims. It reads
+// fh's tracked occupancy for nodeName and considers evicting its pods that have
+// strictly lower priority than pod, lowest priority first, re-running the
+// Filter predicates after each hypothetical eviction. It stops and reports the
+// victims selected so far as soon as the predicates pass, or reports failure if
+// evicting every evictable pod still wouldn't let pod fit.
+//
+// The eviction is hypothetical only for the duration of this check: it removes
+// each candidate from fh's tracked NodeInfo before re-filtering, so Filter
+// plugins that consult fh.GetNodeInfo see the reduced occupancy, and restores
+// every removed pod before returning. If nodeName has no tracked occupancy,
+// there is nothing to evict and pod is checked as-is.
+func (pl *DefaultPreemption) selectVictimsByRerunningFilters(pc *PluginContext, pod *v1.Pod, nodeName string) ([]*v1.Pod, bool) {
+	info := pl.fh.GetNodeInfo(nodeName)
+	if info == nil {
+		return nil, pl.fh.RunFilterPluginsOnNode(pc, pod, nodeName).IsSuccess()
+	}
+
+	var evicted []*v1.Pod
+	defer func() {
+		for _, victim := range evicted {
+			pl.fh.AddPod(victim)
+		}
+	}()
+
+	for _, victim := range evictionOrder(pod, info.Pods) {
+		pl.fh.RemovePod(victim)
+		evicted = append(evicted, victim)
+		if pl.fh.RunFilterPluginsOnNode(pc, pod, nodeName).IsSuccess() {
+			return evicted, true
+		}
+	}
+	return nil, false
+}
+
+// evictionOrder returns the pods in candidates with strictly lower priority
+// than pod, sorted in ascending priority order, so the lowest-priority pods are
+// offered up for eviction first.
+func evictionOrder(pod *v1.Pod, candidates []*v1.Pod) []*v1.Pod {
+	preemptorPriority := podPriority(pod)
+	var victims []*v1.Pod
+	for _, c := range candidates {
+		if podPriority(c) < preemptorPriority {
+			victims = append(victims, c)
+		}
+	}
+	sort.Slice(victims, func(i, j int) bool {
+		return podPriority(victims[i]) < podPriority(victims[j])
+	})
+	return victims
+}
+
+// podPriority returns pod's priority, defaulting to 0 if it isn't set.
+func podPriority(pod *v1.Pod) int32 {
+	if pod.Spec.Priority != nil {
+		return *pod.Spec.Priority
+	}
+	return 0
+}
+
+// PostFilter selects, among the nodes that failed filtering, the first one (in
+// name order, for determinism) where preemption might help and where the pod
+// would fit once its victims are evicted, and nominates it.
+func (pl *DefaultPreemption) PostFilter(pc *PluginContext, pod *v1.Pod, filteredNodeStatusMap NodeToStatusMap) (*PostFilterResult, *Status) {
+	candidates := nodesWherePreemptionMightHelp(filteredNodeStatusMap)
+	if len(candidates) == 0 {
+		return nil, NewStatus(Unschedulable, "no preemption candidates found")
+	}
+
+	for _, nodeName := range candidates {
+		if victims, fits := pl.selectVictims(pc, pod, nodeName); fits {
+			return &PostFilterResult{NominatedNodeName: nodeName, Victims: victims}, nil
+		}
+	}
+
+	return nil, NewStatus(Unschedulable, "preemption did not help schedule the pod on any node")
+}
+
+// nodesWherePreemptionMightHelp returns, in sorted order for determinism, the
+// names of the nodes whose filteredNodeStatusMap entry indicates that removing
+// some of their pods could make the node feasible.
+func nodesWherePreemptionMightHelp(filteredNodeStatusMap NodeToStatusMap) []string {
+	var candidates []string
+	for nodeName, status := range filteredNodeStatusMap {
+		if status.Code() == UnschedulableAndUnresolvable {
+			continue
+		}
+		candidates = append(candidates, nodeName)
+	}
+	sort.Strings(candidates)
+	return candidates
+}
@@ -0,0 +1,822 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog"
+	"k8s.io/kubernetes/pkg/scheduler/apis/config"
+)
+
+// maxTimeout caps how long a Permit plugin may ask the scheduling goroutine to wait.
+const maxTimeout = 15 * time.Minute
+
+// framework is the component responsible for initializing and running
+// scheduler plugins.
+type framework struct {
+	registry Registry
+
+	filterPlugins         []FilterPlugin
+	postFilterPlugins     []PostFilterPlugin
+	preScorePlugins       []PreScorePlugin
+	scorePlugins          []ScorePlugin
+	scorePluginWeight     map[string]int
+	normalizeScorePlugins []NormalizeScorePlugin
+	reservePlugins        []ReservePlugin
+	permitPlugins         []PermitPlugin
+	preBindPlugins        []PreBindPlugin
+	bindPlugins           []BindPlugin
+	postBindPlugins       []PostBindPlugin
+
+	waitingPods     *waitingPodsMap
+	nodeInfos       *nodeInfoMap
+	stopCh          chan struct{}
+	stopOnce        sync.Once
+	metricsRecorder *metricsRecorder
+
+	pluginHealth map[string]*pluginHealthTracker
+}
+
+var _ Framework = &framework{}
+
+// Framework manages the set of plugins in use by the scheduling framework.
+// Configured plugins are called at specified points in a scheduling context.
+type Framework interface {
+	FrameworkHandle
+
+	// RunFilterPlugins runs the set of configured Filter plugins for each of the
+	// given nodes, returning the ones that are feasible along with a map from
+	// the name of every infeasible node to the status that rejected it.
+	RunFilterPlugins(pc *PluginContext, pod *v1.Pod, nodes []*v1.Node) ([]*v1.Node, NodeToStatusMap, *Status)
+	// RunPostFilterPlugins runs the set of configured PostFilter plugins until
+	// one of them successfully nominates a node.
+	RunPostFilterPlugins(pc *PluginContext, pod *v1.Pod, filteredNodeStatusMap NodeToStatusMap) (*PostFilterResult, *Status)
+	// RunPreScorePlugins runs the set of configured PreScore plugins. If any
+	// PreScore plugin returns a non-success status, the scheduling cycle is
+	// aborted.
+	RunPreScorePlugins(pc *PluginContext, pod *v1.Pod, nodes []*v1.Node) *Status
+	// RunScorePlugins runs the set of configured Score plugins, runs their
+	// NormalizeScore method, and applies the configured weight to compute a
+	// final score for each node.
+	RunScorePlugins(pc *PluginContext, pod *v1.Pod, nodes []*v1.Node) (PluginToNodeScoreMap, *Status)
+	// RunNormalizeScorePlugins runs the set of configured NormalizeScore plugins. It
+	// mutates the provided scores in place.
+	RunNormalizeScorePlugins(pc *PluginContext, pod *v1.Pod, scores PluginToNodeScoreMap) *Status
+	// ApplyScoreWeights applies the configured weight of each Score plugin to the
+	// per-plugin node scores computed by that plugin.
+	ApplyScoreWeights(pc *PluginContext, pod *v1.Pod, scores PluginToNodeScoreMap) *Status
+	// RunReservePlugins runs the set of configured Reserve plugins. If any of them
+	// fails, the already-succeeded Reserve plugins have their Unreserve method
+	// called, in order to undo the reservation.
+	RunReservePlugins(pc *PluginContext, pod *v1.Pod, nodeName string) *Status
+	// RunPermitPlugins runs the set of configured Permit plugins. If any of them
+	// asks the pod to wait, the pod is blocked until every such plugin has
+	// allowed it, one of them rejects it, or it times out.
+	RunPermitPlugins(pc *PluginContext, pod *v1.Pod, nodeName string) *Status
+	// RunPreBindPlugins runs the set of configured PreBind plugins. If any of
+	// them returns a non-success status, the pod is rejected and not sent for
+	// binding.
+	RunPreBindPlugins(pc *PluginContext, pod *v1.Pod, nodeName string) *Status
+	// RunBindPlugins runs the set of configured Bind plugins until one handles
+	// the pod (i.e. returns a status other than Skip).
+	RunBindPlugins(pc *PluginContext, pod *v1.Pod, nodeName string) *Status
+	// RunPostBindPlugins runs the set of configured PostBind plugins.
+	RunPostBindPlugins(pc *PluginContext, pod *v1.Pod, nodeName string)
+	// Flush blocks until the metricsRecorder has observed every metric enqueued prior to
+	// the call. It exists so tests can assert on recorded metrics deterministically.
+	Flush()
+	// Stop shuts down the framework's background goroutines, in particular the
+	// metricsRecorder's run loop. It drains any metrics still buffered before
+	// returning. Stop is idempotent and safe to call more than once.
+	Stop()
+}
+
+const (
+	// DefaultBufferSize is the size of the default buffer used by the metricsRecorder.
+	DefaultBufferSize = 1024
+)
+
+// NewFramework initializes plugins given the configured plugin set and returns a
+// Framework that can be used to run them.
+func NewFramework(r Registry, plugins *config.Plugins, args []config.PluginConfig) (Framework, error) {
+	stopCh := make(chan struct{})
+	f := &framework{
+		registry:          r,
+		scorePluginWeight: make(map[string]int),
+		waitingPods:       newWaitingPodsMap(),
+		nodeInfos:         newNodeInfoMap(),
+		stopCh:            stopCh,
+		metricsRecorder:   newMetricsRecorder(stopCh, DefaultBufferSize),
+		pluginHealth:      make(map[string]*pluginHealthTracker),
+	}
+
+	if plugins == nil {
+		return f, nil
+	}
+
+	// tolerant controls how the construction loops below react to a plugin
+	// factory returning an error. By default the framework is strict, matching
+	// prior behavior: a construction error fails NewFramework outright. When
+	// the user opts in via plugins.Tolerant, a failed plugin is instead
+	// retained in PluginStatuses with its ConstructionError set, and framework
+	// initialization continues without it.
+	tolerant := plugins.Tolerant
+
+	pluginConfig := pluginNameToConfig(args)
+
+	// Build the Score plugin list first, since NormalizeScore plugins are
+	// required to already be enabled as Score plugins.
+	if plugins.Score != nil {
+		for _, p := range plugins.Score.Enabled {
+			pg, err := f.newPlugin(p, pluginConfig)
+			if err != nil {
+				if tolerant {
+					f.recordConstructionError(p.Name, err)
+					continue
+				}
+				return nil, err
+			}
+			f.registerPluginHealth(p.Name)
+			sp, ok := pg.(ScorePlugin)
+			if !ok {
+				return nil, fmt.Errorf("plugin %q does not extend score plugin", p.Name)
+			}
+			f.scorePlugins = append(f.scorePlugins, sp)
+			f.scorePluginWeight[p.Name] = weightOrDefault(p.Weight)
+		}
+	}
+
+	if plugins.Filter != nil {
+		for _, p := range plugins.Filter.Enabled {
+			pg, err := f.newPlugin(p, pluginConfig)
+			if err != nil {
+				if tolerant {
+					f.recordConstructionError(p.Name, err)
+					continue
+				}
+				return nil, err
+			}
+			f.registerPluginHealth(p.Name)
+			fp, ok := pg.(FilterPlugin)
+			if !ok {
+				return nil, fmt.Errorf("plugin %q does not extend filter plugin", p.Name)
+			}
+			f.filterPlugins = append(f.filterPlugins, fp)
+		}
+	}
+
+	if plugins.PostFilter != nil {
+		for _, p := range plugins.PostFilter.Enabled {
+			pg, err := f.newPlugin(p, pluginConfig)
+			if err != nil {
+				if tolerant {
+					f.recordConstructionError(p.Name, err)
+					continue
+				}
+				return nil, err
+			}
+			f.registerPluginHealth(p.Name)
+			pfp, ok := pg.(PostFilterPlugin)
+			if !ok {
+				return nil, fmt.Errorf("plugin %q does not extend post-filter plugin", p.Name)
+			}
+			f.postFilterPlugins = append(f.postFilterPlugins, pfp)
+		}
+	}
+
+	if plugins.PreScore != nil {
+		for _, p := range plugins.PreScore.Enabled {
+			pg, err := f.newPlugin(p, pluginConfig)
+			if err != nil {
+				if tolerant {
+					f.recordConstructionError(p.Name, err)
+					continue
+				}
+				return nil, err
+			}
+			f.registerPluginHealth(p.Name)
+			psp, ok := pg.(PreScorePlugin)
+			if !ok {
+				return nil, fmt.Errorf("plugin %q does not extend pre-score plugin", p.Name)
+			}
+			f.preScorePlugins = append(f.preScorePlugins, psp)
+		}
+	}
+
+	if plugins.NormalizeScore != nil {
+		for _, p := range plugins.NormalizeScore.Enabled {
+			if _, ok := f.scorePluginWeight[p.Name]; !ok {
+				return nil, fmt.Errorf("normalizeScore plugin %q is not enabled as a score plugin", p.Name)
+			}
+			pg, err := f.newPlugin(p, pluginConfig)
+			if err != nil {
+				if tolerant {
+					f.recordConstructionError(p.Name, err)
+					continue
+				}
+				return nil, err
+			}
+			f.registerPluginHealth(p.Name)
+			nsp, ok := pg.(NormalizeScorePlugin)
+			if !ok {
+				return nil, fmt.Errorf("plugin %q does not extend normalize score plugin", p.Name)
+			}
+			f.normalizeScorePlugins = append(f.normalizeScorePlugins, nsp)
+		}
+	}
+
+	if plugins.Reserve != nil {
+		for _, p := range plugins.Reserve.Enabled {
+			pg, err := f.newPlugin(p, pluginConfig)
+			if err != nil {
+				if tolerant {
+					f.recordConstructionError(p.Name, err)
+					continue
+				}
+				return nil, err
+			}
+			f.registerPluginHealth(p.Name)
+			rp, ok := pg.(ReservePlugin)
+			if !ok {
+				return nil, fmt.Errorf("plugin %q does not extend reserve plugin", p.Name)
+			}
+			f.reservePlugins = append(f.reservePlugins, rp)
+		}
+	}
+
+	if plugins.Permit != nil {
+		for _, p := range plugins.Permit.Enabled {
+			pg, err := f.newPlugin(p, pluginConfig)
+			if err != nil {
+				if tolerant {
+					f.recordConstructionError(p.Name, err)
+					continue
+				}
+				return nil, err
+			}
+			f.registerPluginHealth(p.Name)
+			pp, ok := pg.(PermitPlugin)
+			if !ok {
+				return nil, fmt.Errorf("plugin %q does not extend permit plugin", p.Name)
+			}
+			f.permitPlugins = append(f.permitPlugins, pp)
+		}
+	}
+
+	if plugins.PreBind != nil {
+		for _, p := range plugins.PreBind.Enabled {
+			pg, err := f.newPlugin(p, pluginConfig)
+			if err != nil {
+				if tolerant {
+					f.recordConstructionError(p.Name, err)
+					continue
+				}
+				return nil, err
+			}
+			f.registerPluginHealth(p.Name)
+			pbp, ok := pg.(PreBindPlugin)
+			if !ok {
+				return nil, fmt.Errorf("plugin %q does not extend pre-bind plugin", p.Name)
+			}
+			f.preBindPlugins = append(f.preBindPlugins, pbp)
+		}
+	}
+
+	if plugins.Bind != nil {
+		for _, p := range plugins.Bind.Enabled {
+			pg, err := f.newPlugin(p, pluginConfig)
+			if err != nil {
+				if tolerant {
+					f.recordConstructionError(p.Name, err)
+					continue
+				}
+				return nil, err
+			}
+			f.registerPluginHealth(p.Name)
+			bp, ok := pg.(BindPlugin)
+			if !ok {
+				return nil, fmt.Errorf("plugin %q does not extend bind plugin", p.Name)
+			}
+			f.bindPlugins = append(f.bindPlugins, bp)
+		}
+	}
+
+	if plugins.PostBind != nil {
+		for _, p := range plugins.PostBind.Enabled {
+			pg, err := f.newPlugin(p, pluginConfig)
+			if err != nil {
+				if tolerant {
+					f.recordConstructionError(p.Name, err)
+					continue
+				}
+				return nil, err
+			}
+			f.registerPluginHealth(p.Name)
+			pbp, ok := pg.(PostBindPlugin)
+			if !ok {
+				return nil, fmt.Errorf("plugin %q does not extend post-bind plugin", p.Name)
+			}
+			f.postBindPlugins = append(f.postBindPlugins, pbp)
+		}
+	}
+
+	return f, nil
+}
+
+// newPlugin looks up the factory for the given plugin name in the registry and
+// constructs it, passing the plugin's configured arguments if any.
+func (f *framework) newPlugin(p config.Plugin, pluginConfig map[string]*runtime.Unknown) (Plugin, error) {
+	factory, ok := f.registry[p.Name]
+	if !ok {
+		return nil, fmt.Errorf("no plugin registered as %q", p.Name)
+	}
+	args := pluginConfig[p.Name]
+	return factory(args, f)
+}
+
+func weightOrDefault(w int) int {
+	if w == 0 {
+		return 1
+	}
+	return w
+}
+
+func pluginNameToConfig(args []config.PluginConfig) map[string]*runtime.Unknown {
+	m := make(map[string]*runtime.Unknown, len(args))
+	for _, a := range args {
+		m[a.Name] = a.Args
+	}
+	return m
+}
+
+// healthTracker returns the health tracker for the named plugin, creating one
+// on first use.
+func (f *framework) healthTracker(name string) *pluginHealthTracker {
+	if t, ok := f.pluginHealth[name]; ok {
+		return t
+	}
+	t := newPluginHealthTracker(name, f.metricsRecorder)
+	f.pluginHealth[name] = t
+	return t
+}
+
+// registerPluginHealth creates a health tracker for a successfully constructed
+// plugin, so later RunXxxPlugins calls can consult its circuit breaker and
+// record invocations against it.
+func (f *framework) registerPluginHealth(name string) {
+	f.healthTracker(name)
+}
+
+// recordConstructionError retains a health tracker for a plugin that failed to
+// construct. It is only called from the tolerant branch of NewFramework's
+// construction loops; a strict framework fails NewFramework instead.
+func (f *framework) recordConstructionError(name string, err error) {
+	f.healthTracker(name).recordConstructionError(err)
+}
+
+// PluginStatuses returns a point-in-time snapshot of every registered plugin's
+// health, sorted by name for deterministic output.
+func (f *framework) PluginStatuses() []PluginStatus {
+	statuses := make([]PluginStatus, 0, len(f.pluginHealth))
+	for _, t := range f.pluginHealth {
+		statuses = append(statuses, t.status())
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// DroppedMetricsCount returns the number of plugin/extension-point duration
+// observations discarded so far because the metrics buffer was full.
+func (f *framework) DroppedMetricsCount() uint64 {
+	return f.metricsRecorder.droppedMetricsCount()
+}
+
+// RunScorePlugins runs the set of configured Score plugins, runs their
+// NormalizeScore method, and then applies the configured weight to compute a
+// final score for each node.
+func (f *framework) RunScorePlugins(pc *PluginContext, pod *v1.Pod, nodes []*v1.Node) (PluginToNodeScoreMap, *Status) {
+	if status := f.RunPreScorePlugins(pc, pod, nodes); !status.IsSuccess() {
+		return nil, status
+	}
+
+	pluginToNodeScores := make(PluginToNodeScoreMap, len(f.scorePlugins))
+	for _, pl := range f.scorePlugins {
+		tracker := f.healthTracker(pl.Name())
+		if tracker.circuitOpen() {
+			continue
+		}
+		nodeScores := make(NodeScoreList, len(nodes))
+		start := time.Now()
+		for i, n := range nodes {
+			score, status := pl.Score(pc, pod, n.Name)
+			if !status.IsSuccess() {
+				tracker.record("Score", status, time.Since(start))
+				return nil, NewStatus(Error, fmt.Sprintf("plugin %q failed with status: %v", pl.Name(), status.Message()))
+			}
+			nodeScores[i] = NodeScore{Name: n.Name, Score: int64(score)}
+		}
+		tracker.record("Score", nil, time.Since(start))
+		pluginToNodeScores[pl.Name()] = nodeScores
+	}
+
+	if status := f.RunNormalizeScorePlugins(pc, pod, pluginToNodeScores); !status.IsSuccess() {
+		return nil, status
+	}
+
+	if status := f.ApplyScoreWeights(pc, pod, pluginToNodeScores); !status.IsSuccess() {
+		return nil, status
+	}
+
+	return pluginToNodeScores, nil
+}
+
+// RunFilterPlugins runs the set of configured Filter plugins against each of the
+// given nodes, returning the nodes that are feasible along with a map from the
+// name of every infeasible node to the status that rejected it.
+func (f *framework) RunFilterPlugins(pc *PluginContext, pod *v1.Pod, nodes []*v1.Node) ([]*v1.Node, NodeToStatusMap, *Status) {
+	failedNodeStatusMap := make(NodeToStatusMap)
+	var feasible []*v1.Node
+	for _, n := range nodes {
+		status := f.RunFilterPluginsOnNode(pc, pod, n.Name)
+		if !status.IsSuccess() {
+			if status.Code() == Error {
+				return nil, nil, status
+			}
+			failedNodeStatusMap[n.Name] = status
+			continue
+		}
+		feasible = append(feasible, n)
+	}
+	return feasible, failedNodeStatusMap, nil
+}
+
+// RunFilterPluginsOnNode runs the set of configured Filter plugins against a
+// single node, identified by name, stopping at the first one that rejects it.
+func (f *framework) RunFilterPluginsOnNode(pc *PluginContext, pod *v1.Pod, nodeName string) *Status {
+	for _, pl := range f.filterPlugins {
+		tracker := f.healthTracker(pl.Name())
+		if tracker.circuitOpen() {
+			continue
+		}
+		start := time.Now()
+		status := pl.Filter(pc, pod, nodeName)
+		tracker.record("Filter", status, time.Since(start))
+		if !status.IsSuccess() {
+			if status.Code() != Unschedulable && status.Code() != UnschedulableAndUnresolvable {
+				return NewStatus(Error, fmt.Sprintf("filter plugin %q failed with status: %v", pl.Name(), status.Message()))
+			}
+			return status
+		}
+	}
+	return NewStatus(Success)
+}
+
+// RunPostFilterPlugins runs the set of configured PostFilter plugins in order,
+// stopping at the first one that returns a Success status with a non-empty
+// NominatedNodeName.
+func (f *framework) RunPostFilterPlugins(pc *PluginContext, pod *v1.Pod, filteredNodeStatusMap NodeToStatusMap) (*PostFilterResult, *Status) {
+	startTime := time.Now()
+	result, status := f.runPostFilterPlugins(pc, pod, filteredNodeStatusMap)
+	f.metricsRecorder.observeExtensionPointDurationAsync("PostFilter", status, time.Since(startTime).Seconds())
+	return result, status
+}
+
+func (f *framework) runPostFilterPlugins(pc *PluginContext, pod *v1.Pod, filteredNodeStatusMap NodeToStatusMap) (*PostFilterResult, *Status) {
+	for _, pl := range f.postFilterPlugins {
+		tracker := f.healthTracker(pl.Name())
+		if tracker.circuitOpen() {
+			continue
+		}
+		start := time.Now()
+		r, status := pl.PostFilter(pc, pod, filteredNodeStatusMap)
+		tracker.record("PostFilter", status, time.Since(start))
+		if status.Code() == Error {
+			msg := fmt.Sprintf("postFilter plugin %q failed with status: %v", pl.Name(), status.Message())
+			klog.Error(msg)
+			return nil, NewStatus(Error, msg)
+		}
+		if status.IsSuccess() && r != nil && r.NominatedNodeName != "" {
+			return r, status
+		}
+	}
+	return nil, NewStatus(Unschedulable, "no postFilter plugin nominated a node")
+}
+
+// RunPreScorePlugins runs the set of configured PreScore plugins. If any of them
+// returns a non-success status, the scheduling cycle is aborted.
+func (f *framework) RunPreScorePlugins(pc *PluginContext, pod *v1.Pod, nodes []*v1.Node) *Status {
+	startTime := time.Now()
+	status := f.runPreScorePlugins(pc, pod, nodes)
+	f.metricsRecorder.observeExtensionPointDurationAsync("PreScore", status, time.Since(startTime).Seconds())
+	return status
+}
+
+func (f *framework) runPreScorePlugins(pc *PluginContext, pod *v1.Pod, nodes []*v1.Node) *Status {
+	for _, pl := range f.preScorePlugins {
+		tracker := f.healthTracker(pl.Name())
+		if tracker.circuitOpen() {
+			continue
+		}
+		start := time.Now()
+		status := pl.PreScore(pc, pod, nodes)
+		tracker.record("PreScore", status, time.Since(start))
+		if !status.IsSuccess() {
+			msg := fmt.Sprintf("preScore plugin %q failed with status: %v", pl.Name(), status.Message())
+			klog.Error(msg)
+			return NewStatus(Error, msg)
+		}
+	}
+	return nil
+}
+
+// RunNormalizeScorePlugins runs the set of configured NormalizeScore plugins. It
+// mutates the provided scores in place.
+func (f *framework) RunNormalizeScorePlugins(pc *PluginContext, pod *v1.Pod, scores PluginToNodeScoreMap) *Status {
+	for _, pl := range f.normalizeScorePlugins {
+		tracker := f.healthTracker(pl.Name())
+		if tracker.circuitOpen() {
+			continue
+		}
+		nodeScoreList, ok := scores[pl.Name()]
+		if !ok {
+			return NewStatus(Error, fmt.Sprintf("no scores found for normalizeScore plugin %q", pl.Name()))
+		}
+		start := time.Now()
+		status := pl.NormalizeScore(pc, nodeScoreList)
+		tracker.record("NormalizeScore", status, time.Since(start))
+		if !status.IsSuccess() {
+			msg := fmt.Sprintf("normalizeScore plugin %q failed with error %v", pl.Name(), status.Message())
+			klog.Error(msg)
+			return NewStatus(Error, msg)
+		}
+	}
+	return nil
+}
+
+// ApplyScoreWeights applies the configured weight of each Score plugin to the
+// per-plugin node scores computed by that plugin.
+func (f *framework) ApplyScoreWeights(pc *PluginContext, pod *v1.Pod, scores PluginToNodeScoreMap) *Status {
+	for _, pl := range f.scorePlugins {
+		nodeScoreList, ok := scores[pl.Name()]
+		if !ok {
+			if f.healthTracker(pl.Name()).circuitOpen() {
+				// The plugin's circuit breaker is open, so it never wrote an
+				// entry for itself this cycle; nothing to weight.
+				continue
+			}
+			return NewStatus(Error, fmt.Sprintf("no scores found for plugin %q", pl.Name()))
+		}
+		weight := f.scorePluginWeight[pl.Name()]
+		for i, nodeScore := range nodeScoreList {
+			nodeScoreList[i] = NodeScore{
+				Name:  nodeScore.Name,
+				Score: nodeScore.Score * int64(weight),
+			}
+		}
+	}
+	return nil
+}
+
+// RunReservePlugins runs the set of configured Reserve plugins. If any of them fails, the
+// already-succeeded Reserve plugins have their Unreserve method called to undo the reservation.
+func (f *framework) RunReservePlugins(pc *PluginContext, pod *v1.Pod, nodeName string) *Status {
+	startTime := time.Now()
+	status := f.runReservePlugins(pc, pod, nodeName)
+	f.metricsRecorder.observeExtensionPointDurationAsync("Reserve", status, time.Since(startTime).Seconds())
+	return status
+}
+
+func (f *framework) runReservePlugins(pc *PluginContext, pod *v1.Pod, nodeName string) *Status {
+	var reserved []ReservePlugin
+	for _, pl := range f.reservePlugins {
+		tracker := f.healthTracker(pl.Name())
+		if tracker.circuitOpen() {
+			continue
+		}
+		start := time.Now()
+		status := pl.Reserve(pc, pod, nodeName)
+		tracker.record("Reserve", status, time.Since(start))
+		if !status.IsSuccess() {
+			msg := fmt.Sprintf("reserve plugin %q failed with status: %v", pl.Name(), status.Message())
+			klog.Error(msg)
+			f.runUnreservePlugins(pc, pod, nodeName, reserved)
+			return NewStatus(Error, msg)
+		}
+		reserved = append(reserved, pl)
+	}
+	return nil
+}
+
+// runUnreservePlugins calls Unreserve on every plugin in plugins, which callers pass as the
+// subset of Reserve plugins that had already succeeded before a later one failed.
+func (f *framework) runUnreservePlugins(pc *PluginContext, pod *v1.Pod, nodeName string, plugins []ReservePlugin) {
+	for _, pl := range plugins {
+		pl.Unreserve(pc, pod, nodeName)
+	}
+}
+
+// RunPermitPlugins runs the set of configured Permit plugins. If any of them asks the pod to
+// wait, the pod is blocked until every such plugin has allowed it, one of them rejects it, or
+// it times out.
+func (f *framework) RunPermitPlugins(pc *PluginContext, pod *v1.Pod, nodeName string) *Status {
+	startTime := time.Now()
+	status := f.runPermitPlugins(pc, pod, nodeName)
+	f.metricsRecorder.observeExtensionPointDurationAsync("Permit", status, time.Since(startTime).Seconds())
+	return status
+}
+
+func (f *framework) runPermitPlugins(pc *PluginContext, pod *v1.Pod, nodeName string) *Status {
+	pluginsWaitTime := make(map[string]time.Duration)
+	shouldWait := false
+	for _, pl := range f.permitPlugins {
+		tracker := f.healthTracker(pl.Name())
+		if tracker.circuitOpen() {
+			continue
+		}
+		start := time.Now()
+		status, d := pl.Permit(pc, pod, nodeName)
+		tracker.record("Permit", status, time.Since(start))
+		if status.IsSuccess() {
+			continue
+		}
+		if status.Code() == Wait {
+			if d > maxTimeout {
+				d = maxTimeout
+			}
+			pluginsWaitTime[pl.Name()] = d
+			shouldWait = true
+			continue
+		}
+		msg := fmt.Sprintf("permit plugin %q failed with status: %v", pl.Name(), status.Message())
+		klog.Error(msg)
+		return NewStatus(Error, msg)
+	}
+
+	if !shouldWait {
+		return nil
+	}
+
+	wp := newWaitingPod(pod, pluginsWaitTime)
+	f.waitingPods.add(wp)
+	defer f.waitingPods.remove(pod.UID)
+
+	klog.V(4).Infof("waiting for pod %q to be allowed by plugins %v", pod.Name, pluginsWaitTime)
+	status := <-wp.s
+	if !status.IsSuccess() {
+		if status.Code() == Unschedulable {
+			return status
+		}
+		return NewStatus(Error, status.Message())
+	}
+	return nil
+}
+
+// RunPreBindPlugins runs the set of configured PreBind plugins. If any of them returns a
+// non-success status, the pod is rejected and not sent for binding.
+func (f *framework) RunPreBindPlugins(pc *PluginContext, pod *v1.Pod, nodeName string) *Status {
+	startTime := time.Now()
+	status := f.runPreBindPlugins(pc, pod, nodeName)
+	f.metricsRecorder.observeExtensionPointDurationAsync("PreBind", status, time.Since(startTime).Seconds())
+	return status
+}
+
+func (f *framework) runPreBindPlugins(pc *PluginContext, pod *v1.Pod, nodeName string) *Status {
+	for _, pl := range f.preBindPlugins {
+		tracker := f.healthTracker(pl.Name())
+		if tracker.circuitOpen() {
+			continue
+		}
+		start := time.Now()
+		status := pl.PreBind(pc, pod, nodeName)
+		tracker.record("PreBind", status, time.Since(start))
+		if !status.IsSuccess() {
+			msg := fmt.Sprintf("preBind plugin %q failed with status: %v", pl.Name(), status.Message())
+			klog.Error(msg)
+			return NewStatus(Error, msg)
+		}
+	}
+	return nil
+}
+
+// RunBindPlugins runs the set of configured Bind plugins until one of them handles the pod, i.e.
+// returns a status other than Skip.
+func (f *framework) RunBindPlugins(pc *PluginContext, pod *v1.Pod, nodeName string) *Status {
+	startTime := time.Now()
+	status := f.runBindPlugins(pc, pod, nodeName)
+	f.metricsRecorder.observeExtensionPointDurationAsync("Bind", status, time.Since(startTime).Seconds())
+	return status
+}
+
+func (f *framework) runBindPlugins(pc *PluginContext, pod *v1.Pod, nodeName string) *Status {
+	if len(f.bindPlugins) == 0 {
+		return NewStatus(Skip, "no bind plugins are configured")
+	}
+	for _, pl := range f.bindPlugins {
+		tracker := f.healthTracker(pl.Name())
+		if tracker.circuitOpen() {
+			continue
+		}
+		start := time.Now()
+		status := pl.Bind(pc, pod, nodeName)
+		tracker.record("Bind", status, time.Since(start))
+		if status != nil && status.Code() == Skip {
+			continue
+		}
+		if !status.IsSuccess() {
+			msg := fmt.Sprintf("bind plugin %q failed with status: %v", pl.Name(), status.Message())
+			klog.Error(msg)
+			return NewStatus(Error, msg)
+		}
+		return status
+	}
+	return NewStatus(Error, "no bind plugin handled the pod")
+}
+
+// RunPostBindPlugins runs the set of configured PostBind plugins.
+func (f *framework) RunPostBindPlugins(pc *PluginContext, pod *v1.Pod, nodeName string) {
+	startTime := time.Now()
+	for _, pl := range f.postBindPlugins {
+		tracker := f.healthTracker(pl.Name())
+		if tracker.circuitOpen() {
+			continue
+		}
+		pluginStart := time.Now()
+		pl.PostBind(pc, pod, nodeName)
+		tracker.record("PostBind", nil, time.Since(pluginStart))
+	}
+	f.metricsRecorder.observeExtensionPointDurationAsync("PostBind", nil, time.Since(startTime).Seconds())
+}
+
+// IterateOverWaitingPods acquires a read lock and iterates over the internal map of waiting
+// pods, calling the provided function for each.
+func (f *framework) IterateOverWaitingPods(callback func(WaitingPod)) {
+	f.waitingPods.iterate(callback)
+}
+
+// GetWaitingPod returns a waiting pod given its UID, or nil if there is currently no waiting
+// pod with that UID.
+func (f *framework) GetWaitingPod(uid types.UID) WaitingPod {
+	if wp := f.waitingPods.get(uid); wp != nil {
+		return wp
+	}
+	// Return an explicit nil interface value rather than a nil *waitingPod, so
+	// callers' nil checks on the returned WaitingPod behave as expected.
+	return nil
+}
+
+// GetNodeInfo returns the framework's current view of nodeName's occupancy, or
+// nil if the framework has no information about that node.
+func (f *framework) GetNodeInfo(nodeName string) *NodeInfo {
+	return f.nodeInfos.get(nodeName)
+}
+
+// SetNode records node as known to the framework.
+func (f *framework) SetNode(node *v1.Node) {
+	f.nodeInfos.setNode(node)
+}
+
+// AddPod records pod as assigned to its Spec.NodeName.
+func (f *framework) AddPod(pod *v1.Pod) {
+	f.nodeInfos.addPod(pod)
+}
+
+// RemovePod removes pod from its Spec.NodeName's tracked occupancy.
+func (f *framework) RemovePod(pod *v1.Pod) {
+	f.nodeInfos.removePod(pod)
+}
+
+// Flush blocks until the metricsRecorder has observed every metric enqueued prior to the call.
+func (f *framework) Flush() {
+	f.metricsRecorder.Flush()
+}
+
+// Stop closes the framework's stopCh, signaling the metricsRecorder's run loop to drain
+// whatever is left in its buffer and exit, and waits for it to do so. It is idempotent:
+// calling it more than once has no additional effect.
+func (f *framework) Stop() {
+	f.stopOnce.Do(func() {
+		close(f.stopCh)
+	})
+	<-f.metricsRecorder.stoppedCh
+}
@@ -19,6 +19,7 @@ package v1alpha1
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -113,10 +114,46 @@ func (pl *TestScorePlugin3) Score(pc *PluginContext, p *v1.Pod, nodeName string)
 	return 0, nil
 }
 
+const preScorePlugin = "prescore-plugin"
+
+var _ = PreScorePlugin(&TestPreScorePlugin{})
+
+// TestPreScorePlugin only implements PreScore, it doesn't extend ScorePlugin.
+type TestPreScorePlugin struct {
+	// If fail is true, PreScore will return an error status.
+	fail bool
+	// numPreScoreCalled records how many times PreScore has been invoked.
+	numPreScoreCalled int
+}
+
+// NewPreScorePlugin is the factory for the PreScore plugin.
+func NewPreScorePlugin(_ *runtime.Unknown, _ FrameworkHandle) (Plugin, error) {
+	return &TestPreScorePlugin{}, nil
+}
+
+// NewPreScorePluginInjectFailure creates a new TestPreScorePlugin which will
+// return an error status for PreScore.
+func NewPreScorePluginInjectFailure(_ *runtime.Unknown, _ FrameworkHandle) (Plugin, error) {
+	return &TestPreScorePlugin{fail: true}, nil
+}
+
+func (pl *TestPreScorePlugin) Name() string {
+	return preScorePlugin
+}
+
+func (pl *TestPreScorePlugin) PreScore(pc *PluginContext, pod *v1.Pod, nodes []*v1.Node) *Status {
+	pl.numPreScoreCalled++
+	if pl.fail {
+		return NewStatus(Error, "injecting failure.")
+	}
+	return nil
+}
+
 var registry = Registry{
-	scorePlugin1: NewNormalizeScorePlugin1,
-	scorePlugin2: NewNormalizeScorePlugin2,
-	scorePlugin3: NewNormalizeScorePlugin3,
+	scorePlugin1:   NewNormalizeScorePlugin1,
+	scorePlugin2:   NewNormalizeScorePlugin2,
+	scorePlugin3:   NewNormalizeScorePlugin3,
+	preScorePlugin: NewPreScorePlugin,
 }
 
 var plugin1 = &config.Plugins{
@@ -153,6 +190,9 @@ var pc = &PluginContext{}
 // Pod is only used for logging errors.
 var pod = &v1.Pod{}
 
+// nodes is only used by PreScore tests.
+var nodes = []*v1.Node{{}, {}}
+
 func TestInitFrameworkWithNormalizeScorePlugins(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -422,3 +462,151 @@ func TestApplyScoreWeights(t *testing.T) {
 		})
 	}
 }
+
+func TestInitFrameworkWithPreScorePlugins(t *testing.T) {
+	tests := []struct {
+		name    string
+		plugins *config.Plugins
+		// If initErr is true, we expect framework initialization to fail.
+		initErr bool
+	}{
+		{
+			name: "enabled PreScore plugin doesn't exist in registry",
+			plugins: &config.Plugins{
+				PreScore: &config.PluginSet{
+					Enabled: []config.Plugin{
+						{Name: "notExist"},
+					},
+				},
+			},
+			initErr: true,
+		},
+		{
+			name: "enabled PreScore plugin doesn't extend the interface",
+			plugins: &config.Plugins{
+				PreScore: &config.PluginSet{
+					Enabled: []config.Plugin{
+						{Name: scorePlugin3},
+					},
+				},
+			},
+			initErr: true,
+		},
+		{
+			name:    "PreScore plugins are nil",
+			plugins: &config.Plugins{PreScore: nil},
+		},
+		{
+			name: "enabled PreScore plugin list is empty",
+			plugins: &config.Plugins{
+				PreScore: &config.PluginSet{
+					Enabled: []config.Plugin{},
+				},
+			},
+		},
+		{
+			name: "valid PreScore plugin",
+			plugins: &config.Plugins{
+				PreScore: &config.PluginSet{
+					Enabled: []config.Plugin{
+						{Name: preScorePlugin},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewFramework(registry, tt.plugins, args)
+			if tt.initErr && err == nil {
+				t.Fatal("Framework initialization should fail")
+			}
+			if !tt.initErr && err != nil {
+				t.Fatalf("Failed to create framework for testing: %v", err)
+			}
+		})
+	}
+}
+
+func TestRunPreScorePlugins(t *testing.T) {
+	tests := []struct {
+		name     string
+		registry Registry
+		plugins  *config.Plugins
+		// If err is true, we expect RunPreScorePlugins to fail.
+		err bool
+	}{
+		{
+			name:     "PreScore plugins are nil",
+			registry: registry,
+			plugins:  &config.Plugins{PreScore: nil},
+		},
+		{
+			name:     "single PreScore plugin returns success",
+			registry: registry,
+			plugins: &config.Plugins{
+				PreScore: &config.PluginSet{
+					Enabled: []config.Plugin{
+						{Name: preScorePlugin},
+					},
+				},
+			},
+		},
+		{
+			name: "single PreScore plugin returns failure",
+			registry: Registry{
+				preScorePlugin: NewPreScorePluginInjectFailure,
+			},
+			plugins: &config.Plugins{
+				PreScore: &config.PluginSet{
+					Enabled: []config.Plugin{
+						{Name: preScorePlugin},
+					},
+				},
+			},
+			err: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewFramework(tt.registry, tt.plugins, args)
+			if err != nil {
+				t.Fatalf("Failed to create framework for testing: %v", err)
+			}
+
+			status := f.RunPreScorePlugins(pc, pod, nodes)
+
+			if tt.err {
+				if status.IsSuccess() {
+					t.Errorf("Expected status to be non-success.")
+				}
+			} else if !status.IsSuccess() {
+				t.Errorf("Expected status to be success.")
+			}
+		})
+	}
+}
+
+// TestFrameworkStop verifies that Stop() reaches the metricsRecorder's run
+// loop through the Framework returned by NewFramework, rather than only being
+// exercisable by constructing a metricsRecorder directly.
+func TestFrameworkStop(t *testing.T) {
+	f, err := NewFramework(Registry{}, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create framework for testing: %v", err)
+	}
+
+	impl := f.(*framework)
+	f.Stop()
+
+	select {
+	case <-impl.metricsRecorder.stoppedCh:
+	case <-time.After(time.Second):
+		t.Fatal("Expected metricsRecorder.run to exit after Stop(), but it is still running")
+	}
+
+	// Stop must be idempotent.
+	f.Stop()
+}
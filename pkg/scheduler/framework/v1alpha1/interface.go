@@ -0,0 +1,366 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package v1alpha1 contains the scheduling framework plugin interfaces and
+// the framework implementation that calls them.
+package v1alpha1
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Code is the Status code/type which is returned from plugins.
+type Code int
+
+// These are predefined codes used in a Status.
+const (
+	// Success means that plugin ran correctly and found pod schedulable.
+	// NOTE: A nil status is also considered as "Success".
+	Success Code = iota
+	// Error is used for internal plugin errors, unexpected input, etc.
+	Error
+	// Unschedulable is used when a plugin finds a pod unschedulable. The scheduler might attempt to
+	// preempt other pods to get this pod scheduled. Use UnschedulableAndUnresolvable to make the
+	// scheduler skip preemption.
+	Unschedulable
+	// UnschedulableAndUnresolvable is used when a plugin finds a pod unschedulable and
+	// preemption would not change that outcome, so the scheduler should skip trying to
+	// find a preemption candidate for this node.
+	UnschedulableAndUnresolvable
+	// Wait is used when a permit plugin finds a pod scheduling should wait.
+	Wait
+	// Skip is used by a Bind plugin to indicate that it chooses not to handle
+	// the pod, deferring to the next Bind plugin in line.
+	Skip
+)
+
+// codeNames maps Code to its string representation, used for metrics labels.
+var codeNames = map[Code]string{
+	Success:                      "Success",
+	Error:                        "Error",
+	Unschedulable:                "Unschedulable",
+	UnschedulableAndUnresolvable: "UnschedulableAndUnresolvable",
+	Wait:                         "Wait",
+	Skip:                         "Skip",
+}
+
+// String converts a Code into a string for logging and metrics labels.
+func (c Code) String() string {
+	if name, ok := codeNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("code %d", int(c))
+}
+
+// Status indicates the result of running a plugin. It consists of a code, and a
+// message. When the status code is not Success, the reasons should explain why.
+type Status struct {
+	code    Code
+	reasons []string
+}
+
+// Code returns code of the Status.
+func (s *Status) Code() Code {
+	if s == nil {
+		return Success
+	}
+	return s.code
+}
+
+// Message returns a concatenated message on reasons of the Status.
+func (s *Status) Message() string {
+	if s == nil {
+		return ""
+	}
+	return strings.Join(s.reasons, ", ")
+}
+
+// IsSuccess returns true if and only if "Status" is nil or Code is "Success".
+func (s *Status) IsSuccess() bool {
+	return s.Code() == Success
+}
+
+// AsError returns nil if the status is a success; otherwise an "error" object
+// is returned with a concatenated message on reasons of the Status.
+func (s *Status) AsError() error {
+	if s.IsSuccess() {
+		return nil
+	}
+	return errors.New(s.Message())
+}
+
+// NewStatus makes a Status out of the given arguments and returns its pointer.
+func NewStatus(code Code, reasons ...string) *Status {
+	return &Status{
+		code:    code,
+		reasons: reasons,
+	}
+}
+
+// PluginContext provides gateway to various plugins to share and retrieve
+// arbitrary data. PluginContext also provides ways to store and retrieve
+// lister/cache objects.
+// PluginContext structure is parallel to the corresponding placement in the scheduler
+// and is not thread safe between plugin extension points.
+type PluginContext struct {
+	mu   sync.RWMutex
+	data map[string]interface{}
+}
+
+// NewPluginContext instantiates a PluginContext object.
+func NewPluginContext() *PluginContext {
+	return &PluginContext{
+		data: make(map[string]interface{}),
+	}
+}
+
+// Read retrieves data with the given "key" from PluginContext. If the key is not
+// present, ok is false.
+func (c *PluginContext) Read(key string) (interface{}, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	v, ok := c.data[key]
+	return v, ok
+}
+
+// Write stores the given "val" in PluginContext with the given "key".
+func (c *PluginContext) Write(key string, val interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data[key] = val
+}
+
+// Delete deletes data with the given key from PluginContext.
+func (c *PluginContext) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.data, key)
+}
+
+// NodeScore is a struct with node name and score.
+type NodeScore struct {
+	Name  string
+	Score int64
+}
+
+// NodeScoreList declares a list of nodes and their scores.
+type NodeScoreList []NodeScore
+
+// PluginToNodeScoreMap declares a map from plugin name to its NodeScoreList.
+type PluginToNodeScoreMap map[string]NodeScoreList
+
+// NodeToStatusMap declares a map from node name to its status.
+type NodeToStatusMap map[string]*Status
+
+// Plugin is the parent type for all the scheduling framework plugins.
+type Plugin interface {
+	Name() string
+}
+
+// FilterPlugin is an interface for Filter plugins. These plugins are called at the
+// filter extension point for filtering out hosts that cannot run a pod.
+type FilterPlugin interface {
+	Plugin
+	// Filter is called by the scheduling framework to filter out nodes that
+	// cannot run the pod. A Status other than Success means the node is
+	// infeasible, and its Code determines whether the scheduler should try
+	// preemption on it: Unschedulable means preemption might help;
+	// UnschedulableAndUnresolvable means it would not.
+	Filter(pc *PluginContext, pod *v1.Pod, nodeName string) *Status
+}
+
+// PostFilterResult carries the output of PostFilter plugins, namely the node, if
+// any, that the framework should nominate for the given pod.
+type PostFilterResult struct {
+	// NominatedNodeName is the node, if any, that was nominated to run pod once
+	// the plugin's preemption/eviction logic frees up enough room on it.
+	NominatedNodeName string
+	// Victims is the set of pods, if any, that must be evicted from
+	// NominatedNodeName before pod will fit there.
+	Victims []*v1.Pod
+}
+
+// PostFilterPlugin is an interface for "PostFilter" plugins. These plugins are
+// called once the Filter phase has produced zero feasible nodes for a pod, and
+// are given a chance to free up resources on a node (e.g. through preemption) so
+// the pod can be retried on it.
+type PostFilterPlugin interface {
+	Plugin
+	// PostFilter is called when no feasible node could be found for a pod. It is
+	// given filteredNodeStatusMap, the reason each node was filtered out. The
+	// first PostFilter plugin that returns a Success status with a non-empty
+	// NominatedNodeName stops the remaining PostFilter plugins from running.
+	PostFilter(pc *PluginContext, pod *v1.Pod, filteredNodeStatusMap NodeToStatusMap) (*PostFilterResult, *Status)
+}
+
+// PreScorePlugin is an interface for "PreScore" plugins. PreScore is called by
+// the scheduling framework after a list of nodes passed the filtering phase, and
+// before any Score plugins run. It is used to perform "pre-scoring" work that is
+// shared across Score plugins, storing any results in the PluginContext for Score
+// plugins to read.
+type PreScorePlugin interface {
+	Plugin
+	// PreScore is called by the scheduling framework after a list of nodes
+	// passed the filtering phase. All PreScore plugins must return success or
+	// the pod will be rejected.
+	PreScore(pc *PluginContext, pod *v1.Pod, nodes []*v1.Node) *Status
+}
+
+// ScorePlugin is an interface that must be implemented by "Score" plugins to
+// rank nodes that passed the filtering phase.
+type ScorePlugin interface {
+	Plugin
+	// Score is called on each filtered node. It must return success and an
+	// integer indicating the rank of the node. All scoring plugins must
+	// return success or the pod will be rejected.
+	Score(pc *PluginContext, p *v1.Pod, nodeName string) (int, *Status)
+}
+
+// NormalizeScorePlugin is an interface that must be implemented by "NormalizeScore"
+// plugins to normalize scores computed by Score plugins before the scores are
+// combined with weights to get the total score for nodes.
+type NormalizeScorePlugin interface {
+	ScorePlugin
+	// NormalizeScore is called for all node scores produced by the same plugin's "Score"
+	// method. A successful run of NormalizeScore will update the scores list and return
+	// a success status.
+	NormalizeScore(pc *PluginContext, scores NodeScoreList) *Status
+}
+
+// ReservePlugin is an interface for Reserve plugins. These plugins are called at
+// the reservation point. These are meant to update the state of the plugin given
+// that a certain pod might take the reservation. This event happens before a pod
+// is scheduled.
+type ReservePlugin interface {
+	Plugin
+	// Reserve is called by the scheduling framework when the scheduler cache is
+	// updated. If this method returns a failed Status, the scheduler will call
+	// the Unreserve method for all enabled ReservePlugins that already
+	// succeeded.
+	Reserve(pc *PluginContext, p *v1.Pod, nodeName string) *Status
+	// Unreserve is called by the scheduling framework when a reserved pod fails
+	// to be scheduled, whether because a later Reserve plugin rejected it or
+	// because of an error. Unreserve is meant to clean up any state associated
+	// with the reserved pod and must be idempotent and able to handle being
+	// called on a pod that was never successfully reserved.
+	Unreserve(pc *PluginContext, p *v1.Pod, nodeName string)
+}
+
+// PermitPlugin is an interface that must be implemented by "Permit" plugins.
+// These plugins are called before a pod is bound to a node.
+type PermitPlugin interface {
+	Plugin
+	// Permit is called before binding a pod (and before prebind plugins). It is
+	// used to prevent or delay the binding of a pod. A permit plugin must return
+	// success or wait with timeout duration, or the pod will be rejected. The
+	// pod will also be rejected if the wait timeout or the pod is rejected while
+	// waiting. Note that if the plugin returns "Wait", the framework will wait
+	// only after all permit plugins have run.
+	Permit(pc *PluginContext, p *v1.Pod, nodeName string) (*Status, time.Duration)
+}
+
+// PreBindPlugin is an interface that must be implemented by "PreBind" plugins.
+// These plugins are called before a pod is bound to a node.
+type PreBindPlugin interface {
+	Plugin
+	// PreBind is called before binding a pod. All prebind plugins must return
+	// success or the pod will be rejected and won't be sent for binding.
+	PreBind(pc *PluginContext, p *v1.Pod, nodeName string) *Status
+}
+
+// BindPlugin is an interface that must be implemented by "Bind" plugins. Bind
+// plugins are used to bind a pod to a node.
+type BindPlugin interface {
+	Plugin
+	// Bind plugins will not be called until all PreBind plugins have
+	// completed. Each bind plugin is called in the configured order. A bind
+	// plugin may choose whether or not to handle the given pod. If a bind
+	// plugin chooses to handle a pod, the remaining bind plugins are skipped.
+	// When a bind plugin does not handle a pod, it must return a Status with
+	// code=Skip.
+	Bind(pc *PluginContext, p *v1.Pod, nodeName string) *Status
+}
+
+// PostBindPlugin is an interface that must be implemented by "PostBind" plugins.
+// These plugins are called after a pod is successfully bound to a node.
+type PostBindPlugin interface {
+	Plugin
+	// PostBind is called after a pod is successfully bound. These plugins are
+	// informational only. A common application of this extension point is for
+	// cleaning up. If a plugin needs to clean up its state, it should implement
+	// this extension point.
+	PostBind(pc *PluginContext, p *v1.Pod, nodeName string)
+}
+
+// WaitingPod represents a pod currently waiting in the permit phase.
+type WaitingPod interface {
+	// GetPod returns a reference to the waiting pod.
+	GetPod() *v1.Pod
+	// Allow declares the waiting pod is allowed to be scheduled, unblocking the
+	// scheduling goroutine it is held in. It should be called by the plugin
+	// that called Wait when that plugin is ready to allow the pod to move on.
+	Allow(pluginName string)
+	// Reject declares the waiting pod unschedulable, unblocking the scheduling
+	// goroutine it is held in, with msg recorded in the returned Status.
+	Reject(msg string)
+}
+
+// PluginFactory is a function that builds a plugin.
+type PluginFactory = func(configuration *runtime.Unknown, f FrameworkHandle) (Plugin, error)
+
+// FrameworkHandle provides data and some tools that plugins can use. It is
+// passed to the plugin factories at the time of plugin initialization. Plugins
+// must store and use this handle to call framework functions.
+type FrameworkHandle interface {
+	// IterateOverWaitingPods acquires a read lock and iterates over the
+	// internal map of waiting pods, calling the provided function for each.
+	IterateOverWaitingPods(callback func(WaitingPod))
+	// GetWaitingPod returns a waiting pod given its UID, or nil if there is
+	// currently no waiting pod with that UID.
+	GetWaitingPod(uid types.UID) WaitingPod
+	// PluginStatuses returns a point-in-time snapshot of the health of every
+	// plugin the framework knows about, including ones that failed to
+	// construct while the framework was running in tolerant mode.
+	PluginStatuses() []PluginStatus
+	// DroppedMetricsCount returns the number of plugin/extension-point duration
+	// observations discarded so far because the metrics buffer was full.
+	DroppedMetricsCount() uint64
+	// RunFilterPluginsOnNode runs the set of configured Filter plugins against a
+	// single node, identified by name, and returns the status of the first one
+	// that rejects it, or a success Status if every plugin accepts it. It lets
+	// plugins built on top of FrameworkHandle (e.g. a PostFilter plugin
+	// re-checking whether a node would fit after freeing up room on it) reuse
+	// the same predicates the Filter phase runs.
+	RunFilterPluginsOnNode(pc *PluginContext, pod *v1.Pod, nodeName string) *Status
+	// GetNodeInfo returns the framework's current view of nodeName's occupancy,
+	// or nil if the framework has no information about that node.
+	GetNodeInfo(nodeName string) *NodeInfo
+	// SetNode records node as known to the framework, so GetNodeInfo can surface it.
+	SetNode(node *v1.Node)
+	// AddPod records pod as assigned to its Spec.NodeName, so GetNodeInfo
+	// includes it in that node's occupancy.
+	AddPod(pod *v1.Pod)
+	// RemovePod removes pod from its Spec.NodeName's tracked occupancy.
+	RemovePod(pod *v1.Pod)
+}
@@ -1,102 +1,162 @@
 package v1alpha1
 
 import (
-	"k8s.io/apimachinery/pkg/util/wait"
+	"sync/atomic"
+	"time"
+
 	k8smetrics "k8s.io/component-base/metrics"
 	"k8s.io/kubernetes/pkg/scheduler/metrics"
-	"sync"
-	"time"
 )
 
+// metricsBufferFlushInterval is the max amount of time that metrics may sit in
+// the metricsRecorder's buffer before being flushed out, even if batchSize
+// hasn't been reached yet.
+const metricsBufferFlushInterval = 1 * time.Second
+
 // frameworkMetric is the data structure passed in the buffer channel between the main framework thread
 // and the metricsRecorder goroutine.
 type frameworkMetric struct {
-	metric *k8smetrics.HistogramVec
+	metric      *k8smetrics.HistogramVec
 	labelValues []string
-	value float64
+	value       float64
+	// done is only set on the sentinel entries enqueued by Flush. The recorder
+	// goroutine closes it once every metric enqueued ahead of it in bufferCh
+	// has been observed, relying on bufferCh's FIFO ordering to establish a
+	// happens-before relationship with previously recorded metrics.
+	done chan struct{}
 }
 
-// metricRecorder records framework metrics in a separate goroutine to avoid overhead in the critical path.
+// metricsRecorder records framework metrics in a separate goroutine to avoid overhead in the critical path.
+// It batches observations: the run() goroutine flushes whenever it has accumulated batchSize metrics, or
+// every flushInterval, whichever comes first.
 type metricsRecorder struct {
 	// bufferCh is a channel that serves as a metrics buffer before the metricsRecorder goroutine reports it.
-	bufferCh   *chan *frameworkMetric
-	batchCh *chan *frameworkMetric
-	// if bufferSize is reached, incoming metrics will be discarded.
+	bufferCh chan *frameworkMetric
+	// batchSize is the number of metrics the goroutine accumulates before observing them as a batch.
 	batchSize int
-
-	mu sync.RWMutex
-
+	// flushInterval bounds how long a metric may sit in the buffer before being observed.
+	flushInterval time.Duration
+	// dropped counts metrics discarded because bufferCh was full when they were recorded.
+	dropped uint64
 
 	// stopCh can be used to stop the metricsRecorder goroutine.
-	stopCh     chan struct{}
-	//
-	stoppedCh  chan struct{}
+	stopCh chan struct{}
+	// stoppedCh is closed once the metricsRecorder goroutine has drained bufferCh and returned.
+	stoppedCh chan struct{}
 }
 
 func newMetricsRecorder(stopCh chan struct{}, bufferSize int) *metricsRecorder {
-	//fmt.Println("Creating recorder... ")
-	bufferCh := make(chan *frameworkMetric, bufferSize)
-	batchCh := make(chan *frameworkMetric, bufferSize)
-	recorder := &metricsRecorder{
-		bufferCh:   &bufferCh,
-		batchCh: &batchCh,
-		stopCh:     stopCh,
-		stoppedCh:  make(chan struct{}),
+	r := &metricsRecorder{
+		bufferCh:      make(chan *frameworkMetric, bufferSize),
+		batchSize:     bufferSize,
+		flushInterval: metricsBufferFlushInterval,
+		stopCh:        stopCh,
+		stoppedCh:     make(chan struct{}),
 	}
-	go wait.Until(recorder.tryCleanUpBuffer, 2*time.Second, recorder.stopCh)
-	//go func() {recorder.run()}()
-	return recorder
+	go r.run()
+	return r
 }
 
+// observeExtensionPointDurationAsync records the duration of a completed extension point
+// without blocking the scheduling goroutine. If bufferCh is full the metric is dropped and
+// accounted for in droppedMetricsCount.
 func (r *metricsRecorder) observeExtensionPointDurationAsync(extensionPoint string, status *Status, value float64) {
-	//fmt.Println("Recording extension point metrics: ")
-
 	newMetric := &frameworkMetric{
-		metric: metrics.FrameworkExtensionPointDuration,
-		labelValues:[]string{extensionPoint, status.Code().String()},
-		value:value,
+		metric:      metrics.FrameworkExtensionPointDuration,
+		labelValues: []string{extensionPoint, status.Code().String()},
+		value:       value,
 	}
 	select {
-	case *r.bufferCh <- newMetric:
-		//fmt.Println("Recording extension point metrics DONE: ", newMetric)
-
+	case r.bufferCh <- newMetric:
 	default:
+		atomic.AddUint64(&r.dropped, 1)
 	}
 }
 
+// observePluginDurationAsync records the duration of a single plugin's invocation without
+// blocking the scheduling goroutine. If bufferCh is full the metric is dropped and accounted
+// for in droppedMetricsCount.
 func (r *metricsRecorder) observePluginDurationAsync(pluginName, extensionPoint string, status *Status, value float64) {
-	//fmt.Println("Recording plugin metrics: ")
 	newMetric := &frameworkMetric{
-		metric: metrics.PluginExecutionDuration,
-		labelValues:[]string{pluginName, extensionPoint, status.Code().String()},
-		value:value,
+		metric:      metrics.PluginExecutionDuration,
+		labelValues: []string{pluginName, extensionPoint, status.Code().String()},
+		value:       value,
 	}
 	select {
-	case *r.bufferCh <- newMetric:
-		//fmt.Println("Recording plugins metrics DONE: ", newMetric)
-
+	case r.bufferCh <- newMetric:
 	default:
+		atomic.AddUint64(&r.dropped, 1)
+	}
+}
+
+// droppedMetricsCount returns the number of metrics discarded so far because bufferCh was full.
+func (r *metricsRecorder) droppedMetricsCount() uint64 {
+	return atomic.LoadUint64(&r.dropped)
+}
+
+// Flush blocks until every metric enqueued on bufferCh prior to the call has been observed by
+// the run() goroutine. Tests use this to assert on recorded metrics deterministically instead
+// of racing the async recorder.
+func (r *metricsRecorder) Flush() {
+	done := make(chan struct{})
+	r.bufferCh <- &frameworkMetric{done: done}
+	<-done
+}
+
+// run collects metrics from bufferCh and observes them in batches of at most batchSize, or
+// every flushInterval, whichever comes first. On stopCh being closed, it drains whatever is
+// left in bufferCh, signals stoppedCh, and returns.
+func (r *metricsRecorder) run() {
+	defer close(r.stoppedCh)
+
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*frameworkMetric, 0, r.batchSize)
+	for {
+		select {
+		case m := <-r.bufferCh:
+			if m.done != nil {
+				r.observeBatch(batch)
+				batch = batch[:0]
+				close(m.done)
+				continue
+			}
+			batch = append(batch, m)
+			if len(batch) >= r.batchSize {
+				r.observeBatch(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			r.observeBatch(batch)
+			batch = batch[:0]
+		case <-r.stopCh:
+			r.observeBatch(batch)
+			r.drain()
+			return
+		}
 	}
 }
 
-// tryCleanUpBuffer tries to clean up the bufferCh by reading at most bufferSize metrics.
-// This is used for testing to make sure metrics are recorded.
-func  (r *metricsRecorder) tryCleanUpBuffer() {
-	//close(r.stopCh)
-	//<-r.stoppedCh
-	// switch channels
-	r.mu.Lock()
-	r.batchCh = r.bufferCh
-	r.bufferCh = r.batchCh
-	r.mu.Unlock()
+// drain observes any metrics still queued in bufferCh without blocking, for use while shutting
+// down the goroutine.
+func (r *metricsRecorder) drain() {
 	for {
-		//fmt.Println("Cleaning up metrics: ", i)
 		select {
-		case m := <- *r.batchCh:
-			//fmt.Println("Got one metric: ", m)
-			m.metric.WithLabelValues(m.labelValues...).Observe(m.value)
-			default:
-				return
+		case m := <-r.bufferCh:
+			if m.done != nil {
+				close(m.done)
+				continue
+			}
+			r.observeBatch([]*frameworkMetric{m})
+		default:
+			return
 		}
 	}
 }
+
+func (r *metricsRecorder) observeBatch(batch []*frameworkMetric) {
+	for _, m := range batch {
+		m.metric.WithLabelValues(m.labelValues...).Observe(m.value)
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	k8smetrics "k8s.io/component-base/metrics"
+)
+
+func TestMetricsRecorderFlush(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	r := newMetricsRecorder(stopCh, 10)
+
+	for i := 0; i < 5; i++ {
+		r.observeExtensionPointDurationAsync("Score", NewStatus(Success), 0.1)
+	}
+	// Flush must not return before the 5 metrics above have been observed,
+	// even though batchSize (10) hasn't been reached.
+	r.Flush()
+}
+
+// TestMetricsRecorderBatchesBySize verifies that run() observes a batch as
+// soon as batchSize is reached, without relying on Flush to force it.
+func TestMetricsRecorderBatchesBySize(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	r := newMetricsRecorder(stopCh, 3)
+	metric := newTestHistogramVec("test_batches_by_size")
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		r.bufferCh <- &frameworkMetric{metric: metric, labelValues: []string{"x"}, value: 0.1}
+	}
+
+	if count := waitForSampleCount(t, metric, "x", 3); count != 3 {
+		t.Fatalf("expected 3 observations once batchSize was reached, got %d", count)
+	}
+	// A batch observed only by the flushInterval ticker would take about
+	// metricsBufferFlushInterval to show up; a size-triggered one does not.
+	if elapsed := time.Since(start); elapsed >= metricsBufferFlushInterval/2 {
+		t.Fatalf("expected the batch to be observed as soon as batchSize was reached, well before flushInterval (%v), but it took %v", metricsBufferFlushInterval, elapsed)
+	}
+}
+
+// TestMetricsRecorderFlushesOnInterval verifies that run() observes whatever
+// is in the buffer once flushInterval elapses, even though batchSize hasn't
+// been reached, without relying on Flush to force it.
+func TestMetricsRecorderFlushesOnInterval(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	r := newMetricsRecorder(stopCh, 10)
+	metric := newTestHistogramVec("test_flushes_on_interval")
+
+	r.bufferCh <- &frameworkMetric{metric: metric, labelValues: []string{"x"}, value: 0.1}
+
+	if count := waitForSampleCount(t, metric, "x", 1); count != 1 {
+		t.Fatalf("expected 1 observation once flushInterval elapsed despite batchSize (10) not being reached, got %d", count)
+	}
+}
+
+func newTestHistogramVec(name string) *k8smetrics.HistogramVec {
+	return k8smetrics.NewHistogramVec(&k8smetrics.HistogramOpts{
+		Name:    name,
+		Help:    "test metric",
+		Buckets: []float64{1},
+	}, []string{"label"})
+}
+
+// waitForSampleCount polls metric's sample count for labelValue until it
+// reaches want or a generous deadline (well past metricsBufferFlushInterval)
+// passes, so the test doesn't race the recorder's background goroutine.
+func waitForSampleCount(t *testing.T, metric *k8smetrics.HistogramVec, labelValue string, want uint64) uint64 {
+	t.Helper()
+	deadline := time.Now().Add(2 * metricsBufferFlushInterval)
+	for {
+		m := &dto.Metric{}
+		if err := metric.WithLabelValues(labelValue).(prometheus.Metric).Write(m); err != nil {
+			t.Fatalf("failed to write metric: %v", err)
+		}
+		if count := m.GetHistogram().GetSampleCount(); count >= want || time.Now().After(deadline) {
+			return count
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"sync"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// NodeInfo is a point-in-time view of a node and the pods currently assigned to
+// it. It is the inventory DefaultPreemption (and any Filter plugin that reasons
+// about node occupancy, e.g. one checking resource requests) consults through
+// FrameworkHandle.GetNodeInfo.
+type NodeInfo struct {
+	Node *v1.Node
+	Pods []*v1.Pod
+}
+
+// Clone returns a NodeInfo backed by a copy of Pods, so callers can remove
+// entries from the copy without mutating the framework's tracked state.
+func (n *NodeInfo) Clone() *NodeInfo {
+	pods := make([]*v1.Pod, len(n.Pods))
+	copy(pods, n.Pods)
+	return &NodeInfo{Node: n.Node, Pods: pods}
+}
+
+// RequestedResources sums the CPU and memory requests of every pod in Pods.
+func (n *NodeInfo) RequestedResources() v1.ResourceList {
+	cpu := resource.Quantity{}
+	mem := resource.Quantity{}
+	for _, pod := range n.Pods {
+		for _, c := range pod.Spec.Containers {
+			cpu.Add(*c.Resources.Requests.Cpu())
+			mem.Add(*c.Resources.Requests.Memory())
+		}
+	}
+	return v1.ResourceList{v1.ResourceCPU: cpu, v1.ResourceMemory: mem}
+}
+
+// nodeInfoMap is a thread-safe store of NodeInfo keyed by node name. It is the
+// framework's tracked view of node occupancy, updated through FrameworkHandle's
+// SetNode, AddPod and RemovePod as pods are scheduled, evicted, or removed.
+type nodeInfoMap struct {
+	mu    sync.RWMutex
+	infos map[string]*NodeInfo
+}
+
+func newNodeInfoMap() *nodeInfoMap {
+	return &nodeInfoMap{infos: make(map[string]*NodeInfo)}
+}
+
+// nodeInfo returns the NodeInfo for nodeName, creating an empty one on first use.
+// Callers must hold mu.
+func (m *nodeInfoMap) nodeInfo(nodeName string) *NodeInfo {
+	info, ok := m.infos[nodeName]
+	if !ok {
+		info = &NodeInfo{}
+		m.infos[nodeName] = info
+	}
+	return info
+}
+
+// setNode records node as known to the map.
+func (m *nodeInfoMap) setNode(node *v1.Node) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nodeInfo(node.Name).Node = node
+}
+
+// addPod records pod as assigned to its Spec.NodeName.
+func (m *nodeInfoMap) addPod(pod *v1.Pod) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	info := m.nodeInfo(pod.Spec.NodeName)
+	info.Pods = append(info.Pods, pod)
+}
+
+// removePod removes pod from its Spec.NodeName's tracked pods, if present.
+func (m *nodeInfoMap) removePod(pod *v1.Pod) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	info, ok := m.infos[pod.Spec.NodeName]
+	if !ok {
+		return
+	}
+	for i, p := range info.Pods {
+		if p.UID == pod.UID {
+			info.Pods = append(info.Pods[:i], info.Pods[i+1:]...)
+			return
+		}
+	}
+}
+
+// get returns a clone of the NodeInfo tracked for nodeName, or nil if the map
+// has no information about it.
+func (m *nodeInfoMap) get(nodeName string) *NodeInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	info, ok := m.infos[nodeName]
+	if !ok {
+		return nil
+	}
+	return info.Clone()
+}
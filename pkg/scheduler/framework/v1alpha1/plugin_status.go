@@ -0,0 +1,188 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/klog"
+)
+
+const (
+	// circuitBreakerThreshold is the number of consecutive Error results a
+	// plugin must return within circuitBreakerWindow before its circuit
+	// breaker trips.
+	circuitBreakerThreshold = 5
+	// circuitBreakerWindow bounds how long a consecutive-error streak may
+	// last, measured from the streak's first Error. An Error that arrives
+	// after the window has elapsed since the streak began starts a new streak
+	// instead of extending the old one.
+	circuitBreakerWindow = 10 * time.Second
+	// circuitBreakerCooldown is how long a tripped plugin's circuit breaker
+	// stays open before the plugin is given another chance to run.
+	circuitBreakerCooldown = 30 * time.Second
+)
+
+// PluginStatus is a point-in-time snapshot of a registered plugin's health, as
+// tracked by the framework across every extension point it implements.
+type PluginStatus struct {
+	// Name is the name the plugin was registered under.
+	Name string
+	// ConstructionError is set if the plugin failed to construct in
+	// NewFramework. It is only ever non-nil when the framework was configured
+	// to tolerate construction failures (plugins.Tolerant); a strict framework
+	// fails NewFramework instead of retaining an errored plugin.
+	ConstructionError error
+	// LastInvoked records the last time each extension point called into this
+	// plugin, keyed by extension point name (e.g. "Filter", "Reserve").
+	LastInvoked map[string]time.Time
+	// NonSuccessCounts counts, per extension point, how many times each
+	// non-Success Code has been returned by this plugin.
+	NonSuccessCounts map[string]map[Code]int
+	// CumulativeLatency is the total time spent across every recorded
+	// invocation of this plugin, fed by the same measurements the
+	// metricsRecorder observes.
+	CumulativeLatency time.Duration
+	// ConsecutiveErrors is the plugin's current streak of consecutive Error
+	// results within circuitBreakerWindow. It resets to zero on any
+	// non-Error result, or when the next Error arrives after the window has
+	// elapsed since the streak began.
+	ConsecutiveErrors int
+	// CircuitOpenUntil is non-zero while the plugin's circuit breaker is
+	// tripped; RunXxxPlugins skips the plugin until this time has passed.
+	CircuitOpenUntil time.Time
+}
+
+// pluginHealthTracker accumulates invocation history for a single plugin and
+// implements the circuit breaker surfaced through PluginStatus.
+type pluginHealthTracker struct {
+	mu sync.Mutex
+
+	name              string
+	constructionError error
+	lastInvoked       map[string]time.Time
+	nonSuccessCounts  map[string]map[Code]int
+	cumulativeLatency time.Duration
+	consecutiveErrors int
+	streakStartedAt   time.Time
+	circuitOpenUntil  time.Time
+
+	// metricsRecorder, if non-nil, receives a per-plugin duration observation
+	// from every call to record, in addition to the in-memory bookkeeping above.
+	metricsRecorder *metricsRecorder
+}
+
+func newPluginHealthTracker(name string, metricsRecorder *metricsRecorder) *pluginHealthTracker {
+	return &pluginHealthTracker{
+		name:             name,
+		lastInvoked:      make(map[string]time.Time),
+		nonSuccessCounts: make(map[string]map[Code]int),
+		metricsRecorder:  metricsRecorder,
+	}
+}
+
+// recordConstructionError marks the plugin as having failed to construct.
+func (t *pluginHealthTracker) recordConstructionError(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.constructionError = err
+}
+
+// record updates the tracker with the outcome of one invocation of the plugin
+// at the given extension point, tripping the circuit breaker once the plugin
+// has failed circuitBreakerThreshold times in a row within circuitBreakerWindow.
+func (t *pluginHealthTracker) record(extensionPoint string, status *Status, latency time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.lastInvoked[extensionPoint] = time.Now()
+	t.cumulativeLatency += latency
+	if t.metricsRecorder != nil {
+		t.metricsRecorder.observePluginDurationAsync(t.name, extensionPoint, status, latency.Seconds())
+	}
+
+	code := status.Code()
+	if code != Success {
+		if t.nonSuccessCounts[extensionPoint] == nil {
+			t.nonSuccessCounts[extensionPoint] = make(map[Code]int)
+		}
+		t.nonSuccessCounts[extensionPoint][code]++
+	}
+
+	if code == Error {
+		now := time.Now()
+		if t.consecutiveErrors == 0 || now.Sub(t.streakStartedAt) > circuitBreakerWindow {
+			t.consecutiveErrors = 0
+			t.streakStartedAt = now
+		}
+		t.consecutiveErrors++
+		if t.consecutiveErrors >= circuitBreakerThreshold && t.circuitOpenUntil.IsZero() {
+			t.circuitOpenUntil = now.Add(circuitBreakerCooldown)
+			klog.Warningf("plugin %q circuit breaker tripped after %d consecutive errors within %v, skipping it until %v", t.name, t.consecutiveErrors, circuitBreakerWindow, t.circuitOpenUntil)
+		}
+		return
+	}
+	t.consecutiveErrors = 0
+}
+
+// circuitOpen reports whether the plugin's circuit breaker is currently
+// tripped. Once the cooldown has elapsed it closes the circuit and resets the
+// consecutive error streak, giving the plugin a clean slate.
+func (t *pluginHealthTracker) circuitOpen() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.circuitOpenUntil.IsZero() {
+		return false
+	}
+	if time.Now().Before(t.circuitOpenUntil) {
+		return true
+	}
+	t.circuitOpenUntil = time.Time{}
+	t.consecutiveErrors = 0
+	t.streakStartedAt = time.Time{}
+	return false
+}
+
+// status returns a snapshot of the tracker's current state.
+func (t *pluginHealthTracker) status() PluginStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lastInvoked := make(map[string]time.Time, len(t.lastInvoked))
+	for k, v := range t.lastInvoked {
+		lastInvoked[k] = v
+	}
+	nonSuccessCounts := make(map[string]map[Code]int, len(t.nonSuccessCounts))
+	for ep, counts := range t.nonSuccessCounts {
+		c := make(map[Code]int, len(counts))
+		for code, n := range counts {
+			c[code] = n
+		}
+		nonSuccessCounts[ep] = c
+	}
+
+	return PluginStatus{
+		Name:              t.name,
+		ConstructionError: t.constructionError,
+		LastInvoked:       lastInvoked,
+		NonSuccessCounts:  nonSuccessCounts,
+		CumulativeLatency: t.cumulativeLatency,
+		ConsecutiveErrors: t.consecutiveErrors,
+		CircuitOpenUntil:  t.circuitOpenUntil,
+	}
+}
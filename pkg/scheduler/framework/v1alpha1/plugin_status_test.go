@@ -0,0 +1,164 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/kubernetes/pkg/scheduler/apis/config"
+)
+
+func TestPluginHealthTrackerCircuitBreaker(t *testing.T) {
+	tracker := newPluginHealthTracker("flaky-plugin", nil)
+
+	for i := 0; i < circuitBreakerThreshold-1; i++ {
+		tracker.record("Filter", NewStatus(Error, "boom"), time.Millisecond)
+		if tracker.circuitOpen() {
+			t.Fatalf("circuit tripped after only %d consecutive errors, want %d", i+1, circuitBreakerThreshold)
+		}
+	}
+
+	tracker.record("Filter", NewStatus(Error, "boom"), time.Millisecond)
+	if !tracker.circuitOpen() {
+		t.Fatalf("circuit did not trip after %d consecutive errors", circuitBreakerThreshold)
+	}
+
+	// A success in between would have reset the streak.
+	tracker2 := newPluginHealthTracker("recovering-plugin", nil)
+	for i := 0; i < circuitBreakerThreshold-1; i++ {
+		tracker2.record("Filter", NewStatus(Error, "boom"), time.Millisecond)
+	}
+	tracker2.record("Filter", nil, time.Millisecond)
+	tracker2.record("Filter", NewStatus(Error, "boom"), time.Millisecond)
+	if tracker2.circuitOpen() {
+		t.Fatalf("circuit tripped even though a success reset the consecutive error streak")
+	}
+}
+
+func TestPluginHealthTrackerCircuitBreakerWindow(t *testing.T) {
+	tracker := newPluginHealthTracker("slowly-flaky-plugin", nil)
+
+	for i := 0; i < circuitBreakerThreshold-1; i++ {
+		tracker.record("Filter", NewStatus(Error, "boom"), time.Millisecond)
+	}
+	if tracker.circuitOpen() {
+		t.Fatalf("circuit tripped after only %d consecutive errors, want %d", circuitBreakerThreshold-1, circuitBreakerThreshold)
+	}
+
+	// Simulate the streak having started outside the window: the next error
+	// should start a fresh streak rather than tripping the breaker.
+	tracker.mu.Lock()
+	tracker.streakStartedAt = time.Now().Add(-circuitBreakerWindow - time.Second)
+	tracker.mu.Unlock()
+
+	tracker.record("Filter", NewStatus(Error, "boom"), time.Millisecond)
+	if tracker.circuitOpen() {
+		t.Fatalf("circuit tripped even though the prior errors fell outside circuitBreakerWindow")
+	}
+
+	status := tracker.status()
+	if status.ConsecutiveErrors != 1 {
+		t.Errorf("expected the out-of-window error to start a new streak of 1, got %d", status.ConsecutiveErrors)
+	}
+}
+
+func TestPluginHealthTrackerCooldown(t *testing.T) {
+	tracker := newPluginHealthTracker("flaky-plugin", nil)
+	for i := 0; i < circuitBreakerThreshold; i++ {
+		tracker.record("Reserve", NewStatus(Error, "boom"), time.Millisecond)
+	}
+	if !tracker.circuitOpen() {
+		t.Fatalf("expected circuit to be open immediately after tripping")
+	}
+
+	// Simulate the cooldown having elapsed.
+	tracker.mu.Lock()
+	tracker.circuitOpenUntil = time.Now().Add(-time.Second)
+	tracker.mu.Unlock()
+
+	if tracker.circuitOpen() {
+		t.Fatalf("expected circuit to close once the cooldown has elapsed")
+	}
+
+	status := tracker.status()
+	if status.ConsecutiveErrors != 0 {
+		t.Errorf("expected consecutive error streak to reset after cooldown, got %d", status.ConsecutiveErrors)
+	}
+	if !status.CircuitOpenUntil.IsZero() {
+		t.Errorf("expected CircuitOpenUntil to be cleared after cooldown, got %v", status.CircuitOpenUntil)
+	}
+}
+
+func TestNewFrameworkTolerantMode(t *testing.T) {
+	constructionErr := errors.New("injected construction failure")
+	r := Registry{
+		scorePlugin1: NewNormalizeScorePlugin1,
+		"bad-plugin": func(_ *runtime.Unknown, _ FrameworkHandle) (Plugin, error) {
+			return nil, constructionErr
+		},
+	}
+	plugins := &config.Plugins{
+		Score: &config.PluginSet{
+			Enabled: []config.Plugin{
+				{Name: scorePlugin1},
+				{Name: "bad-plugin"},
+			},
+		},
+		Tolerant: true,
+	}
+
+	f, err := NewFramework(r, plugins, nil)
+	if err != nil {
+		t.Fatalf("Expected tolerant NewFramework to succeed despite a construction error, got: %v", err)
+	}
+
+	statuses := f.PluginStatuses()
+	var found bool
+	for _, s := range statuses {
+		if s.Name != "bad-plugin" {
+			continue
+		}
+		found = true
+		if s.ConstructionError == nil {
+			t.Errorf("Expected bad-plugin's PluginStatus to carry its construction error")
+		}
+	}
+	if !found {
+		t.Errorf("Expected bad-plugin to be retained in PluginStatuses under tolerant mode, got: %+v", statuses)
+	}
+}
+
+func TestNewFrameworkStrictModeFailsOnConstructionError(t *testing.T) {
+	constructionErr := errors.New("injected construction failure")
+	r := Registry{
+		"bad-plugin": func(_ *runtime.Unknown, _ FrameworkHandle) (Plugin, error) {
+			return nil, constructionErr
+		},
+	}
+	plugins := &config.Plugins{
+		Score: &config.PluginSet{
+			Enabled: []config.Plugin{{Name: "bad-plugin"}},
+		},
+	}
+
+	if _, err := NewFramework(r, plugins, nil); err == nil {
+		t.Fatalf("Expected strict (default) NewFramework to fail when a plugin fails to construct")
+	}
+}
@@ -0,0 +1,311 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kubernetes/pkg/scheduler/apis/config"
+)
+
+// TestPostFilterPlugin returns a configurable result/status from PostFilter and
+// records whether it was called.
+type TestPostFilterPlugin struct {
+	name   string
+	result *PostFilterResult
+	status *Status
+	called bool
+}
+
+func (pl *TestPostFilterPlugin) Name() string { return pl.name }
+
+func (pl *TestPostFilterPlugin) PostFilter(pc *PluginContext, pod *v1.Pod, filteredNodeStatusMap NodeToStatusMap) (*PostFilterResult, *Status) {
+	pl.called = true
+	return pl.result, pl.status
+}
+
+func TestRunPostFilterPluginsShortCircuit(t *testing.T) {
+	declined := &TestPostFilterPlugin{name: "declined-plugin", status: NewStatus(Unschedulable, "can't help")}
+	nominates := &TestPostFilterPlugin{name: "nominating-plugin", result: &PostFilterResult{NominatedNodeName: "node1"}, status: NewStatus(Success)}
+	neverRun := &TestPostFilterPlugin{name: "never-run-plugin", result: &PostFilterResult{NominatedNodeName: "node2"}, status: NewStatus(Success)}
+
+	r := Registry{
+		declined.name:  func(_ *runtime.Unknown, _ FrameworkHandle) (Plugin, error) { return declined, nil },
+		nominates.name: func(_ *runtime.Unknown, _ FrameworkHandle) (Plugin, error) { return nominates, nil },
+		neverRun.name:  func(_ *runtime.Unknown, _ FrameworkHandle) (Plugin, error) { return neverRun, nil },
+	}
+	plugins := &config.Plugins{
+		PostFilter: &config.PluginSet{
+			Enabled: []config.Plugin{
+				{Name: declined.name},
+				{Name: nominates.name},
+				{Name: neverRun.name},
+			},
+		},
+	}
+
+	f, err := NewFramework(r, plugins, nil)
+	if err != nil {
+		t.Fatalf("Failed to create framework for testing: %v", err)
+	}
+
+	result, status := f.RunPostFilterPlugins(pc, pod, NodeToStatusMap{})
+	if !status.IsSuccess() {
+		t.Fatalf("Expected status to be success, got: %v", status.Message())
+	}
+	if result == nil || result.NominatedNodeName != "node1" {
+		t.Errorf("Expected node1 to be nominated, got: %+v", result)
+	}
+	if !declined.called || !nominates.called {
+		t.Errorf("Expected both declined and nominating plugins to run")
+	}
+	if neverRun.called {
+		t.Errorf("Expected the plugin after the nominating one to be skipped")
+	}
+}
+
+func TestDefaultPreemption(t *testing.T) {
+	tests := []struct {
+		name                  string
+		filteredNodeStatusMap NodeToStatusMap
+		selectVictims         func(pc *PluginContext, pod *v1.Pod, nodeName string) ([]*v1.Pod, bool)
+		wantNominated         string
+		wantErr               bool
+	}{
+		{
+			name: "preemption succeeds on the only candidate",
+			filteredNodeStatusMap: NodeToStatusMap{
+				"node1": NewStatus(Unschedulable, "insufficient cpu"),
+			},
+			selectVictims: func(pc *PluginContext, pod *v1.Pod, nodeName string) ([]*v1.Pod, bool) { return nil, true },
+			wantNominated: "node1",
+		},
+		{
+			name: "preemption fails because every node is unresolvable",
+			filteredNodeStatusMap: NodeToStatusMap{
+				"node1": NewStatus(UnschedulableAndUnresolvable, "node selector mismatch"),
+				"node2": NewStatus(UnschedulableAndUnresolvable, "node selector mismatch"),
+			},
+			selectVictims: func(pc *PluginContext, pod *v1.Pod, nodeName string) ([]*v1.Pod, bool) { return nil, true },
+			wantErr:       true,
+		},
+		{
+			name: "preemption fails because the pod still wouldn't fit",
+			filteredNodeStatusMap: NodeToStatusMap{
+				"node1": NewStatus(Unschedulable, "insufficient cpu"),
+			},
+			selectVictims: func(pc *PluginContext, pod *v1.Pod, nodeName string) ([]*v1.Pod, bool) { return nil, false },
+			wantErr:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pl := &DefaultPreemption{selectVictims: tt.selectVictims}
+			result, status := pl.PostFilter(pc, pod, tt.filteredNodeStatusMap)
+
+			if tt.wantErr {
+				if status.IsSuccess() {
+					t.Errorf("Expected status to be non-success.")
+				}
+				return
+			}
+			if !status.IsSuccess() {
+				t.Fatalf("Expected status to be success, got: %v", status.Message())
+			}
+			if result == nil || result.NominatedNodeName != tt.wantNominated {
+				t.Errorf("Expected %q to be nominated, got: %+v", tt.wantNominated, result)
+			}
+		})
+	}
+}
+
+// TestFilterPlugin rejects every node in rejectNodes with status, and accepts
+// all others.
+type TestFilterPlugin struct {
+	name        string
+	rejectNodes map[string]bool
+	status      *Status
+}
+
+func (pl *TestFilterPlugin) Name() string { return pl.name }
+
+func (pl *TestFilterPlugin) Filter(pc *PluginContext, pod *v1.Pod, nodeName string) *Status {
+	if pl.rejectNodes[nodeName] {
+		return pl.status
+	}
+	return NewStatus(Success)
+}
+
+// TestNewDefaultPreemptionDoesNotAssumeFit verifies that the selectVictims
+// wired up by NewDefaultPreemption actually re-runs the Filter plugins
+// registered on the framework through fh, rather than assuming every node fits
+// once considered for preemption.
+func TestNewDefaultPreemptionDoesNotAssumeFit(t *testing.T) {
+	filter := &TestFilterPlugin{
+		name: "test-filter",
+		rejectNodes: map[string]bool{
+			"node1": true,
+		},
+		status: NewStatus(Unschedulable, "insufficient cpu"),
+	}
+
+	r := Registry{
+		filter.name:           func(_ *runtime.Unknown, _ FrameworkHandle) (Plugin, error) { return filter, nil },
+		DefaultPreemptionName: NewDefaultPreemption,
+	}
+	plugins := &config.Plugins{
+		Filter: &config.PluginSet{
+			Enabled: []config.Plugin{{Name: filter.name}},
+		},
+		PostFilter: &config.PluginSet{
+			Enabled: []config.Plugin{{Name: DefaultPreemptionName}},
+		},
+	}
+
+	f, err := NewFramework(r, plugins, nil)
+	if err != nil {
+		t.Fatalf("Failed to create framework for testing: %v", err)
+	}
+
+	filteredNodeStatusMap := NodeToStatusMap{
+		"node1": NewStatus(Unschedulable, "insufficient cpu"),
+		"node2": NewStatus(Unschedulable, "insufficient cpu"),
+	}
+	result, status := f.RunPostFilterPlugins(pc, pod, filteredNodeStatusMap)
+	if !status.IsSuccess() {
+		t.Fatalf("Expected status to be success, got: %v", status.Message())
+	}
+	if result == nil || result.NominatedNodeName != "node2" {
+		t.Errorf("Expected node2 to be nominated (node1 still fails the filter, and has no tracked victims to evict), got: %+v", result)
+	}
+}
+
+// resourceFilterPlugin rejects a node if the sum of the CPU requests of its
+// tracked pods (via fh.GetNodeInfo), plus pod's own CPU request, would exceed
+// the node's allocatable CPU. Unlike TestFilterPlugin, it actually reasons
+// about node occupancy, so it can exercise DefaultPreemption's real victim
+// selection rather than a predicate keyed purely on node name.
+type resourceFilterPlugin struct {
+	fh FrameworkHandle
+}
+
+func (pl *resourceFilterPlugin) Name() string { return "resource-filter" }
+
+func (pl *resourceFilterPlugin) Filter(pc *PluginContext, pod *v1.Pod, nodeName string) *Status {
+	info := pl.fh.GetNodeInfo(nodeName)
+	if info == nil || info.Node == nil {
+		return NewStatus(Success)
+	}
+	requested := info.RequestedResources()
+	total := requested[v1.ResourceCPU].DeepCopy()
+	total.Add(podCPURequest(pod))
+	if total.Cmp(*info.Node.Status.Allocatable.Cpu()) > 0 {
+		return NewStatus(Unschedulable, "insufficient cpu")
+	}
+	return NewStatus(Success)
+}
+
+func podCPURequest(pod *v1.Pod) resource.Quantity {
+	total := resource.Quantity{}
+	for _, c := range pod.Spec.Containers {
+		total.Add(*c.Resources.Requests.Cpu())
+	}
+	return total
+}
+
+// TestNewDefaultPreemptionSelectsVictims verifies that DefaultPreemption, wired
+// up through NewDefaultPreemption, evicts a tracked lower-priority pod from a
+// node's occupancy and re-checks the Filter predicates, rather than re-running
+// them unchanged.
+func TestNewDefaultPreemptionSelectsVictims(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{v1.ResourceCPU: resource.MustParse("2")},
+		},
+	}
+
+	lowPriority := int32(0)
+	highPriority := int32(100)
+	victim := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{UID: types.UID("victim")},
+		Spec: v1.PodSpec{
+			NodeName: "node1",
+			Priority: &lowPriority,
+			Containers: []v1.Container{{
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1500m")},
+				},
+			}},
+		},
+	}
+	preemptor := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{UID: types.UID("preemptor")},
+		Spec: v1.PodSpec{
+			Priority: &highPriority,
+			Containers: []v1.Container{{
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")},
+				},
+			}},
+		},
+	}
+
+	r := Registry{
+		"resource-filter":     func(_ *runtime.Unknown, fh FrameworkHandle) (Plugin, error) { return &resourceFilterPlugin{fh: fh}, nil },
+		DefaultPreemptionName: NewDefaultPreemption,
+	}
+	plugins := &config.Plugins{
+		Filter:     &config.PluginSet{Enabled: []config.Plugin{{Name: "resource-filter"}}},
+		PostFilter: &config.PluginSet{Enabled: []config.Plugin{{Name: DefaultPreemptionName}}},
+	}
+
+	f, err := NewFramework(r, plugins, nil)
+	if err != nil {
+		t.Fatalf("Failed to create framework for testing: %v", err)
+	}
+
+	f.SetNode(node)
+	f.AddPod(victim)
+
+	filteredNodeStatusMap := NodeToStatusMap{
+		"node1": NewStatus(Unschedulable, "insufficient cpu"),
+	}
+	result, status := f.RunPostFilterPlugins(pc, preemptor, filteredNodeStatusMap)
+	if !status.IsSuccess() {
+		t.Fatalf("Expected status to be success, got: %v", status.Message())
+	}
+	if result == nil || result.NominatedNodeName != "node1" {
+		t.Fatalf("Expected node1 to be nominated once its victim is evicted, got: %+v", result)
+	}
+	if len(result.Victims) != 1 || result.Victims[0].UID != victim.UID {
+		t.Errorf("Expected the low-priority pod to be selected as a victim, got: %+v", result.Victims)
+	}
+
+	// The victim was only hypothetically evicted while checking feasibility; it
+	// must still be tracked on node1 afterwards.
+	info := f.GetNodeInfo("node1")
+	if info == nil || len(info.Pods) != 1 || info.Pods[0].UID != victim.UID {
+		t.Errorf("Expected the victim pod to remain tracked on node1 after selection, got: %+v", info)
+	}
+}
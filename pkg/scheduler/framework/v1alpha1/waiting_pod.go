@@ -0,0 +1,148 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// waitingPodsMap is a thread-safe map keyed by pod UID, holding every pod that
+// is currently blocked in the Permit phase. Entries are added by RunPermitPlugins
+// and removed once the corresponding scheduling goroutine stops waiting. Entries
+// can be Allow()ed or Reject()ed from any goroutine, e.g. from event handlers
+// reacting to cluster state changes.
+type waitingPodsMap struct {
+	pods map[types.UID]*waitingPod
+	mu   sync.RWMutex
+}
+
+func newWaitingPodsMap() *waitingPodsMap {
+	return &waitingPodsMap{
+		pods: make(map[types.UID]*waitingPod),
+	}
+}
+
+// add adds a new WaitingPod to the map.
+func (m *waitingPodsMap) add(wp *waitingPod) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pods[wp.GetPod().UID] = wp
+}
+
+// remove removes a WaitingPod from the map.
+func (m *waitingPodsMap) remove(uid types.UID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.pods, uid)
+}
+
+// get returns a WaitingPod with the given UID, or nil if it isn't waiting.
+func (m *waitingPodsMap) get(uid types.UID) *waitingPod {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.pods[uid]
+}
+
+// iterate acquires a read lock and iterates over the map of WaitingPods.
+func (m *waitingPodsMap) iterate(callback func(WaitingPod)) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, wp := range m.pods {
+		callback(wp)
+	}
+}
+
+// waitingPod represents a pod currently blocked in the Permit phase, waiting on
+// one or more PermitPlugins to Allow it or for its own timer to expire.
+type waitingPod struct {
+	pod *v1.Pod
+	s   chan *Status
+
+	mu sync.RWMutex
+	// pendingPlugins maps the name of every permit plugin that asked to wait to
+	// the timer that will reject the pod once that plugin's wait time elapses.
+	pendingPlugins map[string]*time.Timer
+}
+
+var _ WaitingPod = &waitingPod{}
+
+// newWaitingPod creates a waitingPod and starts a timer for each plugin that
+// returned a Wait status, using the duration it requested.
+func newWaitingPod(pod *v1.Pod, pluginsMaxWaitTime map[string]time.Duration) *waitingPod {
+	wp := &waitingPod{
+		pod:            pod,
+		s:              make(chan *Status, 1),
+		pendingPlugins: make(map[string]*time.Timer, len(pluginsMaxWaitTime)),
+	}
+
+	for plugin, waitTime := range pluginsMaxWaitTime {
+		plugin, waitTime := plugin, waitTime
+		wp.pendingPlugins[plugin] = time.AfterFunc(waitTime, func() {
+			msg := fmt.Sprintf("rejected due to timeout after waiting %v at plugin %v", waitTime, plugin)
+			wp.Reject(msg)
+		})
+	}
+
+	return wp
+}
+
+// GetPod returns a reference to the waiting pod.
+func (w *waitingPod) GetPod() *v1.Pod {
+	return w.pod
+}
+
+// Allow declares that the plugin named pluginName is done waiting on this pod.
+// Once every plugin that asked to wait has called Allow, the pod is unblocked
+// with a success status.
+func (w *waitingPod) Allow(pluginName string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if timer, exist := w.pendingPlugins[pluginName]; exist {
+		timer.Stop()
+		delete(w.pendingPlugins, pluginName)
+	}
+
+	// Only unblock the pod once every pending plugin has allowed it.
+	if len(w.pendingPlugins) != 0 {
+		return
+	}
+
+	select {
+	case w.s <- NewStatus(Success):
+	default:
+	}
+}
+
+// Reject declares the waiting pod unschedulable, unblocking the scheduling
+// goroutine it is held in.
+func (w *waitingPod) Reject(msg string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, timer := range w.pendingPlugins {
+		timer.Stop()
+	}
+
+	select {
+	case w.s <- NewStatus(Unschedulable, msg):
+	default:
+	}
+}